@@ -0,0 +1,195 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Household registration addresses, as printed on ID cards and expected on
+// petitions, write 鄰, 巷, 弄 and 號 with Arabic numerals and 段 and 樓 with
+// Chinese numerals, e.g. 臺北市大安區光明里5鄰忠孝東路四段123巷4弄5之1號七樓.
+
+var (
+	addressPostalCodePattern   = regexp.MustCompile(`^\d{3}(\d{2,3})?`)
+	addressMunicipalityPattern = regexp.MustCompile(`^\p{Han}{2}[縣市]`)
+	addressDistrictPattern     = regexp.MustCompile(`^\p{Han}{1,3}?[區鄉鎮市]`)
+	addressFloorPattern        = regexp.MustCompile(`(\d+)[Ff]`)
+	addressChineseUnitPattern  = regexp.MustCompile(`([〇零一二三四五六七八九十百千兩]+)(鄰|巷|弄|號)`)
+	addressChineseSubPattern   = regexp.MustCompile(`之([〇零一二三四五六七八九十百千兩]+)`)
+	addressArabicUnitPattern   = regexp.MustCompile(`(\d+)(鄰|巷|弄|號)`)
+	addressArabicSubPattern    = regexp.MustCompile(`之0*(\d+)`)
+	addressChineseNumPattern   = regexp.MustCompile(`(\d+)(段|樓)`)
+)
+
+func sanitizeAddress(address string) string {
+	address = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, normalizeInput(address))
+
+	address = strings.ReplaceAll(address, "台", "臺")
+	address = addressPostalCodePattern.ReplaceAllString(address, "")
+	address = dedupeAddressPrefix(address)
+
+	address = strings.NewReplacer("-", "之", "~", "之").Replace(address)
+	address = addressFloorPattern.ReplaceAllString(address, "${1}樓")
+
+	address = addressChineseUnitPattern.ReplaceAllStringFunc(address, func(match string) string {
+		m := addressChineseUnitPattern.FindStringSubmatch(match)
+		if n := chineseToNumber(m[1]); n > 0 {
+			return strconv.Itoa(n) + m[2]
+		}
+		return match
+	})
+	address = addressChineseSubPattern.ReplaceAllStringFunc(address, func(match string) string {
+		m := addressChineseSubPattern.FindStringSubmatch(match)
+		if n := chineseToNumber(m[1]); n > 0 {
+			return "之" + strconv.Itoa(n)
+		}
+		return match
+	})
+
+	address = addressArabicUnitPattern.ReplaceAllStringFunc(address, func(match string) string {
+		m := addressArabicUnitPattern.FindStringSubmatch(match)
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n == 0 {
+			return match
+		}
+		return strconv.Itoa(n) + m[2]
+	})
+	address = addressArabicSubPattern.ReplaceAllString(address, "之${1}")
+
+	address = addressChineseNumPattern.ReplaceAllStringFunc(address, func(match string) string {
+		m := addressChineseNumPattern.FindStringSubmatch(match)
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return match
+		}
+		if chinese := numberToChinese(n); chinese != "" {
+			return chinese + m[2]
+		}
+		return match
+	})
+
+	return address
+}
+
+// dedupeAddressPrefix removes a municipality or district typed twice, which
+// happens when people pick the municipality from a menu and then paste their
+// full address.
+func dedupeAddressPrefix(address string) string {
+	municipality := addressMunicipalityPattern.FindString(address)
+	if municipality == "" {
+		return address
+	}
+
+	rest := strings.TrimPrefix(address, municipality)
+	for strings.HasPrefix(rest, municipality) {
+		rest = strings.TrimPrefix(rest, municipality)
+	}
+
+	if district := addressDistrictPattern.FindString(rest); district != "" {
+		tail := strings.TrimPrefix(rest, district)
+		for strings.HasPrefix(tail, district) {
+			tail = strings.TrimPrefix(tail, district)
+		}
+		rest = district + tail
+	}
+
+	return municipality + rest
+}
+
+var chineseDigits = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+// numberToChinese writes n in Chinese numerals as used in addresses, e.g.
+// 10 as 十, 11 as 十一 and 101 as 一百零一. It returns "" outside 1 to 9999.
+func numberToChinese(n int) string {
+	if n <= 0 || n > 9999 {
+		return ""
+	}
+
+	units := []struct {
+		value int
+		name  string
+	}{{1000, "千"}, {100, "百"}, {10, "十"}, {1, ""}}
+
+	var b strings.Builder
+	zero := false
+	for _, u := range units {
+		d := n / u.value
+		n %= u.value
+
+		if d == 0 {
+			zero = b.Len() > 0
+			continue
+		}
+		if zero {
+			b.WriteString(chineseDigits[0])
+			zero = false
+		}
+		// 十一 rather than 一十一 when the number starts at the tens
+		if !(u.value == 10 && d == 1 && b.Len() == 0) {
+			b.WriteString(chineseDigits[d])
+		}
+		b.WriteString(u.name)
+	}
+
+	return b.String()
+}
+
+// chineseToNumber reads Chinese numerals such as 十二, 一百零五 or 二〇五. It
+// returns 0 when s is not a number.
+func chineseToNumber(s string) int {
+	digits := map[rune]int{
+		'〇': 0, '零': 0, '一': 1, '二': 2, '兩': 2, '三': 3, '四': 4,
+		'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+	}
+	units := map[rune]int{'十': 10, '百': 100, '千': 1000}
+
+	// digit by digit, as in 二〇五
+	positional := true
+	for _, r := range s {
+		if _, ok := units[r]; ok {
+			positional = false
+			break
+		}
+	}
+	if positional {
+		n := 0
+		for _, r := range s {
+			d, ok := digits[r]
+			if !ok {
+				return 0
+			}
+			n = n*10 + d
+		}
+		return n
+	}
+
+	total, digit := 0, -1
+	for _, r := range s {
+		if d, ok := digits[r]; ok {
+			digit = d
+			continue
+		}
+
+		u, ok := units[r]
+		if !ok {
+			return 0
+		}
+		if digit == -1 {
+			digit = 1 // 十 alone means 一十
+		}
+		total += digit * u
+		digit = -1
+	}
+	if digit > 0 {
+		total += digit
+	}
+
+	return total
+}
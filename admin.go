@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The form layout calibration tool is only served in dev mode: it writes to
+// json-config and has no authentication.
+
+var formLayoutNamePattern = regexp.MustCompile(`^stage-(\d+)-([^/\\.]+)$`)
+
+// FormLayoutSizes are the font size classes of inputField in preview.css.
+var FormLayoutSizes = []string{"xl", "lg", "md", "sm", "addr-xsm"}
+
+type FormLayoutEntry struct {
+	Name      string
+	Image     string
+	HasLayout bool
+}
+
+// FormLayoutEntries lists every petition form background in FormImagesDir
+// and whether it has a layout yet.
+func (ctrl *Controller) FormLayoutEntries() ([]*FormLayoutEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(FormImagesDir, "stage-*.png"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*FormLayoutEntry{}
+	for _, p := range paths {
+		image := filepath.Base(p)
+		name := strings.TrimSuffix(image, ".png")
+		if !formLayoutNamePattern.MatchString(name) {
+			continue
+		}
+
+		entries = append(entries, &FormLayoutEntry{
+			Name:      name,
+			Image:     image,
+			HasLayout: ctrl.FormLayout(name) != nil,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// NewDefaultFormLayout places the fields where most stage 2 forms have them,
+// as a starting point for calibrating a new form.
+func NewDefaultFormLayout(image string) *FormLayout {
+	return &FormLayout{
+		Image: image,
+		Fields: []*FormField{
+			{Field: FormFieldName, Size: "md", Left: 22, Top: 26.1, LetterSpacing: 0.2},
+			{Field: FormFieldIdNumber, Size: "md", Left: 22.4, Top: 30.7, LetterSpacing: 0.71},
+			{Field: FormFieldBirthDate, Size: "sm", Left: 38.8, Top: 29.7, LetterSpacing: 0.02},
+			{Field: FormFieldAddress, Size: "addr-xsm", Left: 61.8, Top: 18.2, Width: 32.8, Height: 25},
+			{Field: FormFieldSignature, Left: 83.1, Top: 30.8, Width: 7.6, Height: 7.3},
+		},
+	}
+}
+
+// FormLayoutsAdmin lists the petition forms (GET /admin/form-layouts) or opens
+// the calibration tool for one of them (GET /admin/form-layouts/<name>).
+func (ctrl *Controller) FormLayoutsAdmin(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/form-layouts"), "/")
+	if name == "" {
+		entries, err := ctrl.FormLayoutEntries()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		ctrl.renderTemplate(w, r, "admin-form-layouts.html", map[string]interface{}{
+			"BaseURL": ctrl.AppBaseURL.String(),
+			"Entries": entries,
+		})
+		return
+	}
+
+	m := formLayoutNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
+		return
+	}
+
+	layout := ctrl.FormLayout(name)
+	if layout == nil {
+		layout = NewDefaultFormLayout(name + ".png")
+		if err := layout.Validate(); err != nil {
+			ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
+			return
+		}
+	}
+
+	sample := NewSamplePreviewData(ctrl.AppBaseURL, ctrl.AppBaseURL, m[2], "", 0)
+	idNumber := (&FormField{Field: FormFieldIdNumber}).Lines(sample)
+	ctrl.renderTemplate(w, r, "admin-form-layout.html", map[string]interface{}{
+		"BaseURL":    ctrl.AppBaseURL.String(),
+		"Name":       name,
+		"SaveURL":    ctrl.AppBaseURL.JoinPath("apis", "admin", "form-layouts", name).String(),
+		"PreviewURL": ctrl.AppBaseURL.JoinPath("preview", "stages", m[1], m[2]).String(),
+		"Layout":     layout,
+		"Fields":     FormFields,
+		"Sizes":      FormLayoutSizes,
+		"Sample": map[string]string{
+			FormFieldName:         sample.Name,
+			FormFieldIdNumber:     idNumber[0],
+			"year":                sample.BirthYear,
+			"month":               sample.BirthMonth,
+			"day":                 sample.BirthDate,
+			FormFieldAddress:      sample.Address,
+			FormFieldMobileNumber: sample.MobileNumber,
+		},
+	})
+}
+
+type RespSaveFormLayout struct {
+	Message string                `json:"message"`
+	Result  *ResultSaveFormLayout `json:"result,omitempty"`
+}
+
+type ResultSaveFormLayout struct {
+	Layout *FormLayout `json:"layout"`
+}
+
+// SaveFormLayout validates a layout from the calibration tool, writes it to
+// JSONConfigFormLayoutsDir and swaps it in, so the next preview uses it
+// (PUT /apis/admin/form-layouts/<name>).
+func (ctrl *Controller) SaveFormLayout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", http.MethodPut)
+		writeJSON(w, http.StatusMethodNotAllowed, RespSaveFormLayout{
+			Message: http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/apis/admin/form-layouts/")
+	if !formLayoutNamePattern.MatchString(name) {
+		writeJSON(w, http.StatusNotFound, RespSaveFormLayout{
+			Message: http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	layout := &FormLayout{}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(layout); err != nil {
+		writeJSON(w, http.StatusBadRequest, RespSaveFormLayout{Message: err.Error()})
+		return
+	}
+
+	if err := layout.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, RespSaveFormLayout{Message: err.Error()})
+		return
+	}
+
+	data, err := json.MarshalIndent(layout, "", "\t")
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	path := ctrl.DataPath(JSONConfigFormLayoutsDir, name+".json")
+	if err := writeFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		writeJSON(w, http.StatusInternalServerError, RespSaveFormLayout{Message: err.Error()})
+		return
+	}
+
+	ctrl.SetFormLayout(name, layout)
+
+	writeJSON(w, http.StatusOK, RespSaveFormLayout{
+		Message: http.StatusText(http.StatusOK),
+		Result:  &ResultSaveFormLayout{Layout: layout},
+	})
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// App serves every request from the current snapshot of the config, so the
+// admin console can apply a change by loading a new snapshot and swapping it
+// in while requests keep being served from the old one.
+type App struct {
+	Settings *Settings     // fixed for the life of the process
+	Accounts AdminAccounts // nil when the admin console is disabled
+	Sessions *AdminSessions
+	Audit    *AuditLog
+
+	mu            sync.Mutex // serializes reloads and writes to json-config
+	snapshot      atomic.Pointer[Snapshot]
+	transitionsAt time.Time // transitions due by then have been applied
+}
+
+// Snapshot is a loaded config with the controller and routes serving it.
+type Snapshot struct {
+	*Controller
+	handler http.Handler
+}
+
+func NewApp(s *Settings) (*App, error) {
+	a := &App{Settings: s, Sessions: NewAdminSessions()}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+
+	cfg := a.Controller().Config
+	if cfg.AdminAccountsPath != "" {
+		var err error
+		a.Accounts, err = ReadConfigAdminAccounts(cfg.AdminAccountsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.Audit = NewAuditLog(cfg.AuditLogPath)
+
+	now := time.Now()
+	if err := a.auditStartup(now); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+
+	if err := a.applyTransitions(now); err != nil {
+		return nil, fmt.Errorf("transitions: %w", err)
+	}
+
+	return a, nil
+}
+
+// auditStartup records how the loaded legislators differ from the audit
+// state, e.g. after a deploy changed json-config, and restores when
+// transitions were last applied. The first start only saves the state.
+func (a *App) auditStartup(now time.Time) error {
+	state, err := a.Audit.ReadState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return a.Audit.WriteState(a.auditState())
+	}
+	a.transitionsAt = state.TransitionsAppliedAt
+
+	old, err := state.Config()
+	if err != nil {
+		return err
+	}
+
+	entries := DiffCampaigns(old, a.Controller().Config)
+	if len(entries) > 0 {
+		log.Printf("startup: %d legislators changed since the last audit entry", len(entries))
+	}
+
+	return a.record(entries, now, "startup", AuditSourceStartup)
+}
+
+// record appends entries to the audit log as done by actor and saves the
+// audit state of the current snapshot. It does nothing without entries.
+func (a *App) record(entries []*AuditEntry, now time.Time, actor, source string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	for _, e := range entries {
+		e.Time = now
+		e.Actor = actor
+		e.Source = source
+	}
+
+	if err := a.Audit.Append(entries...); err != nil {
+		return err
+	}
+
+	return a.Audit.WriteState(a.auditState())
+}
+
+// auditState returns the audit state of the current snapshot.
+func (a *App) auditState() *AuditState {
+	s := NewAuditState(a.Controller().Config)
+	s.TransitionsAppliedAt = a.transitionsAt
+	return s
+}
+
+// Controller returns the controller of the current snapshot.
+func (a *App) Controller() *Controller {
+	return a.snapshot.Load().Controller
+}
+
+// Reload loads json-config and the templates again, e.g. on SIGHUP, and
+// records the legislators that changed in the audit log as done by actor.
+func (a *App) Reload(actor string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	old := a.Controller().Config
+	if err := a.reload(); err != nil {
+		return err
+	}
+
+	entries := DiffCampaigns(old, a.Controller().Config)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := a.record(entries, time.Now(), actor, AuditSourceReload); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+
+	log.Printf("reload: %d legislators changed", len(entries))
+	return nil
+}
+
+// reload loads the config and templates and swaps them in. The current
+// snapshot is kept if anything fails to load. a.mu must be held, except while
+// the App is created.
+func (a *App) reload() error {
+	cfg, err := LoadConfig(a.Settings)
+	if err != nil {
+		return err
+	}
+
+	tmpls, err := NewTemplateRegistry(cfg, filepath.Join(cfg.TemplatesDir, "*.html"))
+	if err != nil {
+		return fmt.Errorf("template error: %w", err)
+	}
+
+	ctrl := NewController(cfg, tmpls)
+	a.snapshot.Store(&Snapshot{Controller: ctrl, handler: a.routes(ctrl)})
+	return nil
+}
+
+// ApplyTransitions applies the scheduled transitions that came due since the
+// last call and records them in the audit log.
+func (a *App) ApplyTransitions(now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.applyTransitions(now)
+}
+
+// applyTransitions writes the edits of the transitions that came due after
+// a.transitionsAt and by now to json-config, like admin edits, and records
+// what they changed. Each transition thus fires once, and an admin edit made
+// after it is kept. a.mu must be held, except while the App is created.
+func (a *App) applyTransitions(now time.Time) error {
+	now = now.In(Location)
+	if !now.After(a.transitionsAt) {
+		return nil
+	}
+
+	cfg := a.Controller().Config
+	terms := []uint64{}
+	for term := range cfg.Campaigns {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i] < terms[j]
+	})
+
+	changed := []*AuditEntry{}
+	for _, term := range terms {
+		edits := cfg.Campaigns[term].dueTransitions(a.transitionsAt, now)
+		if len(edits) == 0 {
+			continue
+		}
+
+		entries, err := a.updateRecallLegislators(term, edits)
+		if err != nil {
+			return fmt.Errorf("terms/%d: %w", term, err)
+		}
+		changed = append(changed, changedEntries(entries)...)
+	}
+
+	for _, e := range changed {
+		logTransition(e)
+	}
+
+	a.transitionsAt = now
+	if len(changed) == 0 {
+		return a.Audit.WriteState(a.auditState())
+	}
+
+	return a.record(changed, now, "scheduler", AuditSourceSchedule)
+}
+
+func logTransition(e *AuditEntry) {
+	for _, c := range e.Changes {
+		log.Printf("transition: %d/%s %s: %s %s -> %s", e.Term, e.ConstituencyCode, e.PoliticianName, c.Field, rawOrNull(c.From), rawOrNull(c.To))
+	}
+}
+
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.snapshot.Load().handler.ServeHTTP(w, r)
+}
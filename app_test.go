@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestSettings copies json-config into a temporary directory, so tests
+// can edit it, and keeps the audit log there too.
+func newTestSettings(t *testing.T) *Settings {
+	t.Helper()
+
+	dir := t.TempDir()
+	err := filepath.WalkDir("json-config", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, path)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := DefaultSettings()
+	s.Hostname = "localhost:8080"
+	s.DataDir = filepath.Join(dir, "json-config")
+	s.AuditLog = filepath.Join(dir, "audit.jsonl")
+
+	return s
+}
+
+// editTestLegislator changes the first legislator of term 11 in json-config.
+func editTestLegislator(t *testing.T, s *Settings, edit func(row jsonObject) jsonObject) string {
+	t.Helper()
+
+	path := filepath.Join(s.DataDir, JSONConfigTermsDir, "11", JSONConfigRecallLegislators)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []jsonObject{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatal(err)
+	}
+	rows[0] = edit(rows[0])
+
+	if err := os.WriteFile(path, marshalJSONUnescaped(rows), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var code string
+	json.Unmarshal(rows[0].Get("constituencyCode"), &code)
+	return code
+}
+
+func TestAuditStartup(t *testing.T) {
+	s := newTestSettings(t)
+
+	a, err := NewApp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := a.Audit.Query(AuditQuery{}); len(entries) != 0 {
+		t.Fatalf("first start recorded %d entries, want none", len(entries))
+	}
+
+	code := editTestLegislator(t, s, func(row jsonObject) jsonObject {
+		return row.Set("csoURL", json.RawMessage(`"https://example.tw/deployed"`))
+	})
+
+	a, err = NewApp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := a.Audit.Query(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Source != AuditSourceStartup || entries[0].ConstituencyCode != code {
+		t.Fatalf("entries = %+v, want one startup entry of %s", entries, code)
+	}
+	if c := entries[0].Changes; len(c) != 1 || c[0].Field != "csoURL" {
+		t.Errorf("changes = %+v, want csoURL", c)
+	}
+
+	if _, err := NewApp(s); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := a.Audit.Query(AuditQuery{}); len(entries) != 1 {
+		t.Errorf("restarting without changes recorded %d entries, want 1", len(entries))
+	}
+}
+
+func TestTransitionFiresOnce(t *testing.T) {
+	s := newTestSettings(t)
+	code := editTestLegislator(t, s, func(row jsonObject) jsonObject { return row })
+
+	transitions := `[{"constituencyCode": "` + code + `", "at": "2025-01-01T00:00", "set": {"csoURL": "https://example.tw/scheduled"}}]`
+	path := filepath.Join(s.DataDir, JSONConfigTermsDir, "11", JSONConfigTransitions)
+	if err := os.WriteFile(path, []byte(transitions), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	csoURL := func(a *App) string {
+		return a.Controller().Config.Campaigns[11].GetRecallLegislatorByCode(code).CsoURL
+	}
+
+	a, err := NewApp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := csoURL(a); got != "https://example.tw/scheduled" {
+		t.Fatalf("csoURL after the due transition = %q, want the scheduled one", got)
+	}
+
+	edited := "https://example.tw/edited"
+	if _, err := a.UpdateRecallLegislator(11, code, &RecallLegislatorEdit{CsoURL: &edited}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Reload("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.ApplyTransitions(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := csoURL(a); got != edited {
+		t.Errorf("csoURL after a reload and a tick = %q, want the edit %q", got, edited)
+	}
+
+	a, err = NewApp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := csoURL(a); got != edited {
+		t.Errorf("csoURL after a restart = %q, want the edit %q", got, edited)
+	}
+
+	entries, err := a.Audit.Query(AuditQuery{ConstituencyCode: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduled := 0
+	for _, e := range entries {
+		if e.Source == AuditSourceSchedule {
+			scheduled++
+		}
+	}
+	if scheduled != 1 {
+		t.Errorf("transition recorded %d times, want once", scheduled)
+	}
+}
@@ -2,9 +2,41 @@ const filteredCandidateContainer = document.getElementById('filtered-candidate-c
 const shareContainer = document.getElementById('share-container');
 const pepTalk = document.querySelector(`.pep-talk`);
 
+// messages and formats are rendered by the page in the negotiated locale.
+function t(key, params = {}) {
+	const message = (typeof messages !== "undefined" && messages[key]) || key;
+	return message.replace(/\{(\w+)\}/g, (match, name) => Object.hasOwn(params, name) ? params[name] : match);
+}
+
+function formatDate(date) {
+	if (!date) {
+		return "";
+	}
+
+	const [, month, day] = date.split("-").map(Number);
+	return formats["month_day"]
+		.replace("{monthName}", formats[`month.${month}`] || month)
+		.replace("{month}", month)
+		.replace("{day}", day);
+}
+
+// timeLeft counts down to an instant sent by the server, using the clock of the
+// browser rather than the time the page was rendered.
+function timeLeft(end) {
+	const left = Math.max(0, new Date(end) - Date.now());
+	return {
+		hours: Math.floor(left / 3600000),
+		minutes: Math.floor(left % 3600000 / 60000),
+	};
+}
+
 async function sendAjaxRequest(municipality, district, ward) {
 	let params = new URLSearchParams();
 
+	if (typeof term !== "undefined" && term !== "") {
+		params.append("term", term);
+	}
+
 	if (municipality !== null && municipality !== undefined) {
 		params.append("municipality", municipality);
 	}
@@ -132,6 +164,48 @@ document.addEventListener("DOMContentLoaded", () => {
 			});
 	});
 	
+	// the button is left out when the campaign has no ward boundaries
+	const locateBtn = document.getElementById("locate-btn");
+	if (locateBtn && !("geolocation" in navigator)) {
+		locateBtn.style.display = "none";
+	}
+
+	locateBtn?.addEventListener("click", () => {
+		mask.classList.add('active');
+		filteredCandidateContainer.innerHTML = "";
+		shareContainer.style.display = "none";
+
+		navigator.geolocation.getCurrentPosition(async (position) => {
+			let params = new URLSearchParams({
+				lat: position.coords.latitude,
+				lng: position.coords.longitude,
+			});
+			if (typeof term !== "undefined" && term !== "") {
+				params.append("term", term);
+			}
+
+			try {
+				const response = await fetch(`${baseURL}/apis/locate?${params.toString()}`);
+				const data = await response.json();
+				if (!Object.hasOwn(data, "result")) {
+					alert(t("locate.no_ward"));
+				} else if (!data.result.legislators || data.result.legislators.length === 0) {
+					showShareContainer();
+				} else {
+					const address = data.result.municipality.n + data.result.district.n + data.result.ward.n;
+					showFilteredCandidateContainer(data.result.legislators, address);
+				}
+			} catch (error) {
+				console.error(error);
+			} finally {
+				mask.classList.remove('active');
+			}
+		}, () => {
+			mask.classList.remove('active');
+			alert(t("locate.unavailable"));
+		}, { enableHighAccuracy: true, timeout: 10000 });
+	});
+
 	dialogMask.addEventListener("click", function(event) {
 		if (event.target === dialogClose || dialogClose.contains(event.target)) {
 			dialogMask.style.display = "none";
@@ -158,25 +232,25 @@ function showFilteredCandidateContainer(legislators, address) {
 				case "ABORTED":
 					recallFailedClass = "recall-failed";
 					recallStages = `<div class="recall-stage-failed-flow">
-						<h4>您選區的連署未能及時送件...</h4>
-						別灰心，我們還是需要您的力量，支持其他選區進行中的罷免活動，幫忙分享資訊！
+						<h4>${t("legislator.aborted")}</h4>
+						${t("legislator.pep_talk")}
 					</div>`;
-					candidateAction = `<button class="btn-black lg w100" onclick="shareCurrentLink('')"><i class="icon-link"></i>幫忙分享資訊！</button>`;
+					candidateAction = `<button class="btn-black lg w100" onclick="shareCurrentLink('')"><i class="icon-link"></i>${t("share")}</button>`;
 					break;
 
 				case "FAILED":
 					recallFailedClass = "recall-failed";
 					recallStages = `<div class="recall-stage-failed-flow">
-						<h4>您選區的連署未通過...</h4>
-						別灰心，我們還是需要您的力量，支持其他選區進行中的罷免活動，幫忙分享資訊！
+						<h4>${t("legislator.failed")}</h4>
+						${t("legislator.pep_talk")}
 					</div>`;
-					candidateAction = `<button class="btn-black lg w100" onclick="shareCurrentLink('')"><i class="icon-link"></i>幫忙分享資訊！</button>`;
+					candidateAction = `<button class="btn-black lg w100" onclick="shareCurrentLink('')"><i class="icon-link"></i>${t("share")}</button>`;
 					break;
 
 				default:
 					recallStages = [1, 2, 3].map(stage => `
 						<h4 class="recall-stage ${stage === legislator.recallStage ? 'active' : ''}">
-							<span>第 ${stage} 階段</span>${stage === 3 ? "罷免投票" : "連署罷免"}
+							<span>${t("stage", { stage })}</span>${stage === 3 ? t("stage.vote") : t("stage.petition")}
 						</h4>
 						${stage < 3 ? '<span class="icon-step-arrow"></span>' : ''}
 					`).join('');
@@ -187,12 +261,12 @@ function showFilteredCandidateContainer(legislators, address) {
 
 					if (legislator.recallStage === 1 || legislator.recallStage === 2) {
 						if (legislator.formDeployed) {
-							candidateAction = `<a href="${legislator.participateURL}?address=${address}"><button class="btn-primary lg">連署罷免</button></a>`;
+							candidateAction = `<a href="${legislator.participateURL}?address=${address}"><button class="btn-primary lg">${t("legislator.sign")}</button></a>`;
 						} else {
-							candidateAction = `<button class="btn-primary lg" disabled>${legislator.recallStage} 階準備中</button>`
+							candidateAction = `<button class="btn-primary lg" disabled>${t("legislator.stage_preparing", { stage: legislator.recallStage })}</button>`
 						}
 					} else {
-						candidateAction = `<a href="${legislator.calendarURL}" target="_blank"><button class="btn-primary lg w100">加入 Google 日曆提醒投票</button></a>`;
+						candidateAction = `<a href="${legislator.calendarURL}" target="_blank"><button class="btn-primary lg w100">${t("legislator.google_calendar")}</button></a>`;
 					}
 					break;
 			}
@@ -211,14 +285,16 @@ function showFilteredCandidateContainer(legislators, address) {
 								${legislator.daysLeft < 15 ? '<i class="icon-urgent"></i>' : ''} 
 								${legislator.daysLeft < 0 ? '<i class="icon-urgent"></i>' : ''} 
 								${legislator.daysLeft > 0
-									? `${legislator.safetyCutoffDateStr} 截止，剩餘 ${legislator.daysLeft} 天`
-									: `請儘速繳交，罷團已開始造冊`}
+									? t("legislator.days_left", { date: formatDate(legislator.safetyCutoffDate), days: legislator.daysLeft })
+									: legislator.safetyCutoffDate && legislator.daysLeft === 0
+										? t("legislator.hours_left", timeLeft(legislator.safetyCutoffEnd))
+										: t("legislator.overdue")}
 							</div>
 						</div>
 						${candidateAction}
 					</div>
 				</div>
-				${legislator.recallStatus === "ONGOING" ? "<p>罷免需經兩個階段連署，兩階段都通過後才進行投票決定罷免結果。請大家務必三個階段都完整參與！</p>" : ''}`;
+				${legislator.recallStatus === "ONGOING" ? `<p>${t("legislator.three_stages")}</p>` : ''}`;
 			filteredCandidateContainer.appendChild(candidateContainer);
 		});
 		filteredCandidateContainer.style.display = "flex";
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The audit log records every change to the legislators of a campaign, made
+// in the admin console, by reloading json-config or by deploying a changed
+// one, one JSON entry per line. Next to it, the audit state keeps the
+// legislators as last recorded, so the server can tell at startup what
+// changed while it was down.
+
+const DefaultAuditLogPath = "audit.jsonl"
+
+// AuditStateSuffix is appended to the path of the audit log to name the file
+// of its state.
+const AuditStateSuffix = ".state"
+
+const (
+	AuditSourceAdmin    = "admin"
+	AuditSourceReload   = "reload"
+	AuditSourceSchedule = "schedule"
+	AuditSourceStartup  = "startup"
+)
+
+// AuditEntry records who changed which fields of a legislator, and when.
+type AuditEntry struct {
+	Time             time.Time      `json:"time"`
+	Actor            string         `json:"actor"`
+	Source           string         `json:"source"`
+	Term             uint64         `json:"term"`
+	ConstituencyCode string         `json:"constituencyCode"`
+	PoliticianName   string         `json:"politicianName"`
+	Changes          []*AuditChange `json:"changes"`
+}
+
+// AuditChange is the value of a field of recall-legislators.json before and
+// after a change; null when the legislator was added or removed.
+type AuditChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// auditIgnoredFields are computed when a campaign is loaded rather than read
+// from json-config.
+var auditIgnoredFields = map[string]bool{
+	"participateURL":  true,
+	"daysLeft":        true,
+	"votingDaysLeft":  true,
+	"safetyCutoffEnd": true,
+	"result":          true,
+}
+
+// DiffCampaigns compares the legislators of every campaign in two configs and
+// returns an entry for each legislator that changed, without time, actor and
+// source.
+func DiffCampaigns(old, new *Config) []*AuditEntry {
+	terms := []uint64{}
+	for term := range old.Campaigns {
+		terms = append(terms, term)
+	}
+	for term := range new.Campaigns {
+		if old.Campaigns[term] == nil {
+			terms = append(terms, term)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i] < terms[j]
+	})
+
+	entries := []*AuditEntry{}
+	for _, term := range terms {
+		var ol, nl RecallLegislators
+		if c := old.Campaigns[term]; c != nil {
+			ol = c.RecallLegislators
+		}
+		if c := new.Campaigns[term]; c != nil {
+			nl = c.RecallLegislators
+		}
+
+		entries = append(entries, DiffRecallLegislators(term, ol, nl)...)
+	}
+
+	return entries
+}
+
+// DiffRecallLegislators matches legislators by constituency code, in the order
+// of the new list followed by the removed ones.
+func DiffRecallLegislators(term uint64, old, new RecallLegislators) []*AuditEntry {
+	olds := map[string]*RecallLegislator{}
+	for _, l := range old {
+		olds[l.ConstituencyCode] = l
+	}
+
+	pairs := [][2]*RecallLegislator{}
+	seen := map[string]bool{}
+	for _, l := range new {
+		pairs = append(pairs, [2]*RecallLegislator{olds[l.ConstituencyCode], l})
+		seen[l.ConstituencyCode] = true
+	}
+	for _, l := range old {
+		if !seen[l.ConstituencyCode] {
+			pairs = append(pairs, [2]*RecallLegislator{l, nil})
+		}
+	}
+
+	entries := []*AuditEntry{}
+	for _, p := range pairs {
+		changes := diffAuditFields(auditFields(p[0]), auditFields(p[1]))
+		if len(changes) == 0 {
+			continue
+		}
+
+		l := p[1]
+		if l == nil {
+			l = p[0]
+		}
+
+		entries = append(entries, &AuditEntry{
+			Term:             term,
+			ConstituencyCode: l.ConstituencyCode,
+			PoliticianName:   l.PoliticianName,
+			Changes:          changes,
+		})
+	}
+
+	return entries
+}
+
+// auditFields returns the fields of l as they are in json-config.
+func auditFields(l *RecallLegislator) jsonObject {
+	if l == nil {
+		return nil
+	}
+
+	o := jsonObject{}
+	if err := json.Unmarshal(marshalJSONUnescaped(l), &o); err != nil {
+		panic(err)
+	}
+
+	fields := jsonObject{}
+	for _, m := range o {
+		if !auditIgnoredFields[m.Key] {
+			fields = append(fields, m)
+		}
+	}
+
+	return fields
+}
+
+func diffAuditFields(from, to jsonObject) []*AuditChange {
+	changes := []*AuditChange{}
+	for _, m := range from {
+		if v := to.Get(m.Key); !bytes.Equal(m.Value, v) {
+			changes = append(changes, &AuditChange{Field: m.Key, From: m.Value, To: v})
+		}
+	}
+	for _, m := range to {
+		if from.Get(m.Key) == nil {
+			changes = append(changes, &AuditChange{Field: m.Key, To: m.Value})
+		}
+	}
+
+	return changes
+}
+
+// AuditLog appends entries to a JSON Lines file. Entries are never rewritten.
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+func (l *AuditLog) Append(entries ...*AuditEntry) error {
+	data := []byte{}
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// AuditState is the audited fields of every legislator, by term, as of the
+// last entry of the audit log, and when scheduled transitions were last
+// applied.
+type AuditState struct {
+	Legislators          map[uint64][]jsonObject `json:"legislators"` // uint64: Term
+	TransitionsAppliedAt time.Time               `json:"transitionsAppliedAt"`
+}
+
+func NewAuditState(cfg *Config) *AuditState {
+	s := &AuditState{Legislators: map[uint64][]jsonObject{}}
+	for term, c := range cfg.Campaigns {
+		rows := []jsonObject{}
+		for _, l := range c.RecallLegislators {
+			rows = append(rows, auditFields(l))
+		}
+		s.Legislators[term] = rows
+	}
+
+	return s
+}
+
+// Config returns a config with only the legislators of the state, to diff
+// a loaded config against.
+func (s *AuditState) Config() (*Config, error) {
+	cfg := &Config{Campaigns: map[uint64]*Campaign{}}
+	for term, rows := range s.Legislators {
+		rs := RecallLegislators{}
+		if err := json.Unmarshal(marshalJSONUnescaped(rows), &rs); err != nil {
+			return nil, err
+		}
+		cfg.Campaigns[term] = &Campaign{Term: term, RecallLegislators: rs}
+	}
+
+	return cfg, nil
+}
+
+// ReadState returns the saved state, or nil when there is none yet.
+func (l *AuditLog) ReadState() (*AuditState, error) {
+	data, err := os.ReadFile(l.path + AuditStateSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &AuditState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s%s: %w", l.path, AuditStateSuffix, err)
+	}
+
+	return s, nil
+}
+
+func (l *AuditLog) WriteState(s *AuditState) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return writeFileAtomic(l.path+AuditStateSuffix, marshalJSONUnescaped(s), 0644)
+}
+
+// AuditQuery selects entries of the audit log. Zero values match everything.
+type AuditQuery struct {
+	Term             uint64
+	ConstituencyCode string
+	Limit            int
+}
+
+func (q AuditQuery) Match(e *AuditEntry) bool {
+	return (q.Term == 0 || e.Term == q.Term) &&
+		(q.ConstituencyCode == "" || e.ConstituencyCode == q.ConstituencyCode)
+}
+
+// Query returns the entries matching q, latest first. A missing log has no
+// entries.
+func (l *AuditLog) Query(q AuditQuery) ([]*AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := []*AuditEntry{}
+
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		e := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", l.path, n, err)
+		}
+
+		if q.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Admin accounts are local to the server: a JSON list of usernames with
+// argon2id password hashes and TOTP secrets, usually generated by the
+// admin-account command.
+//
+//	[{"username": "...", "passwordHash": "$argon2id$v=19$...", "totpSecret": "BASE32..."}]
+
+var ErrInvalidPasswordHash = errors.New("invalid argon2id password hash")
+
+// argon2id parameters of new hashes, as recommended by RFC 9106 for
+// memory-constrained servers. Existing hashes keep their own parameters.
+const (
+	passwordHashTime    = 3
+	passwordHashMemory  = 64 * 1024 // KiB
+	passwordHashThreads = 4
+	passwordHashKeyLen  = 32
+	passwordSaltLen     = 16
+)
+
+// TOTP as in RFC 6238 with the defaults authenticator apps expect.
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+	totpSkew   = 1 // periods accepted before and after now
+)
+
+type AdminAccount struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash"`
+	TOTPSecret   string `json:"totpSecret"` // base32, without padding
+
+	totpKey []byte
+
+	mu          sync.Mutex
+	lastCounter uint64 // last TOTP period used, so codes cannot be replayed
+}
+
+type AdminAccounts map[string]*AdminAccount // string: Username
+
+// config: admin accounts
+func ReadConfigAdminAccounts(path string) (AdminAccounts, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows := []*AdminAccount{}
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, fmt.Errorf("admin-accounts: %w", err)
+	}
+
+	accounts := AdminAccounts{}
+	for _, a := range rows {
+		if a.Username == "" {
+			return nil, fmt.Errorf("admin-accounts: username is required")
+		}
+		if _, exists := accounts[a.Username]; exists {
+			return nil, fmt.Errorf("admin-accounts: duplicated username %q", a.Username)
+		}
+
+		if _, err := parsePasswordHash(a.PasswordHash); err != nil {
+			return nil, fmt.Errorf("admin-accounts: %s: %w", a.Username, err)
+		}
+
+		a.totpKey, err = decodeTOTPSecret(a.TOTPSecret)
+		if err != nil {
+			return nil, fmt.Errorf("admin-accounts: %s: totpSecret: %w", a.Username, err)
+		}
+
+		accounts[a.Username] = a
+	}
+
+	return accounts, nil
+}
+
+// Authenticate checks the password and TOTP code of username. Unknown
+// usernames still cost a password hash so they cannot be told apart by
+// timing.
+func (accounts AdminAccounts) Authenticate(username, password, code string, now time.Time) (*AdminAccount, bool) {
+	a, exists := accounts[username]
+	if !exists {
+		VerifyPassword(dummyPasswordHash, password)
+		return nil, false
+	}
+
+	if ok, err := VerifyPassword(a.PasswordHash, password); err != nil || !ok {
+		return nil, false
+	}
+
+	if !a.VerifyTOTP(code, now) {
+		return nil, false
+	}
+
+	return a, true
+}
+
+// VerifyTOTP accepts the code of the current period or of the periods next
+// to it, once.
+func (a *AdminAccount) VerifyTOTP(code string, now time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counter := uint64(now.Unix() / int64(totpPeriod/time.Second))
+	for i := -totpSkew; i <= totpSkew; i++ {
+		c := counter + uint64(i)
+		if c <= a.lastCounter {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totpCode(a.totpKey, c)), []byte(code)) == 1 {
+			a.lastCounter = c
+			return true
+		}
+	}
+
+	return false
+}
+
+func totpCode(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < 10 {
+		return nil, errors.New("must be at least 80 bits")
+	}
+
+	return key, nil
+}
+
+// NewTOTPSecret returns a random 160-bit secret in base32.
+func NewTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// TOTPURI returns the otpauth URI authenticator apps scan as a QR code.
+func TOTPURI(issuer, username, secret string) string {
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + username,
+	}
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+type passwordHash struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// HashPassword returns password hashed with argon2id in the PHC string
+// format, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, passwordHashTime, passwordHashMemory, passwordHashThreads, passwordHashKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, passwordHashMemory, passwordHashTime, passwordHashThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func VerifyPassword(hash, password string) (bool, error) {
+	h, err := parsePasswordHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func parsePasswordHash(hash string) (*passwordHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	h := &passwordHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if h.time < 1 || h.threads < 1 || h.memory < 8*uint32(h.threads) {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	return h, nil
+}
+
+// dummyPasswordHash is verified against when a username does not exist. It
+// uses the same parameters as HashPassword and matches no password anyone
+// would use.
+const dummyPasswordHash = "$argon2id$v=19$m=65536,t=3,p=4$JTSfHs+JZ6pReRTOgAQs4g$to5Rv0vX7ldZLvwAi6awyol6zZSQtEA4aDaxN+vcyWU"
+
+const (
+	AdminSessionCookieName = "admin_session"
+	AdminSessionTTL        = 12 * time.Hour
+)
+
+// Logins of a username are refused for adminLoginLockout after
+// adminLoginMaxFailures failures within it.
+const (
+	adminLoginMaxFailures = 5
+	adminLoginLockout     = 15 * time.Minute
+)
+
+// Logins verify at most adminLoginConcurrency passwords at a time. Each
+// argon2id hash takes 64 MiB, so a burst of logins must not run them all.
+const adminLoginConcurrency = 2
+
+type AdminSession struct {
+	Username string
+	Expires  time.Time
+}
+
+// AdminSessions keeps logged in sessions in memory; restarting the server
+// logs everyone out.
+type AdminSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*AdminSession // string: token
+	failures map[string][]time.Time   // string: username
+	logins   chan struct{}
+}
+
+func NewAdminSessions() *AdminSessions {
+	return &AdminSessions{
+		sessions: map[string]*AdminSession{},
+		failures: map[string][]time.Time{},
+		logins:   make(chan struct{}, adminLoginConcurrency),
+	}
+}
+
+// Create starts a session and returns its token.
+func (s *AdminSessions) Create(username string, now time.Time) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for t, session := range s.sessions {
+		if now.After(session.Expires) {
+			delete(s.sessions, t)
+		}
+	}
+
+	s.sessions[token] = &AdminSession{Username: username, Expires: now.Add(AdminSessionTTL)}
+	delete(s.failures, username)
+
+	return token, nil
+}
+
+func (s *AdminSessions) Get(token string, now time.Time) *AdminSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[token]
+	if !exists {
+		return nil
+	}
+	if now.After(session.Expires) {
+		delete(s.sessions, token)
+		return nil
+	}
+
+	return session
+}
+
+func (s *AdminSessions) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+}
+
+// Locked reports whether username failed to log in too often recently.
+func (s *AdminSessions) Locked(username string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, exists := s.failures[username]
+	if !exists {
+		return false
+	}
+
+	recent := []time.Time{}
+	for _, t := range failures {
+		if now.Sub(t) < adminLoginLockout {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(s.failures, username)
+		return false
+	}
+	s.failures[username] = recent
+
+	return len(recent) >= adminLoginMaxFailures
+}
+
+// Fail counts a failed login of username, which must be an existing
+// account so guessed usernames do not fill the map.
+func (s *AdminSessions) Fail(username string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[username] = append(s.failures[username], now)
+}
+
+// BeginLogin reserves one of the adminLoginConcurrency password checks. It
+// returns false without waiting when all are in use; otherwise the caller
+// must call EndLogin.
+func (s *AdminSessions) BeginLogin() bool {
+	select {
+	case s.logins <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *AdminSessions) EndLogin() {
+	<-s.logins
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	VotingStartHour = 8
+	VotingEndHour   = 16
+)
+
+// VotingHours returns the opening and closing time of the polling stations on
+// the given voting date (YYYY-MM-DD).
+func VotingHours(date string, loc *time.Location) (time.Time, time.Time, error) {
+	d, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	start := time.Date(d.Year(), d.Month(), d.Day(), VotingStartHour, 0, 0, 0, loc)
+	end := time.Date(d.Year(), d.Month(), d.Day(), VotingEndHour, 0, 0, 0, loc)
+	return start, end, nil
+}
+
+type ICSEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Alarms      []time.Duration
+}
+
+// ICS renders a single-event iCalendar (RFC 5545) document.
+func (e ICSEvent) ICS(now time.Time) []byte {
+	const layout = "20060102T150405Z"
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//OurTaiwan//recall-2025//ZH-TW",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:" + e.UID,
+		"DTSTAMP:" + now.UTC().Format(layout),
+		"DTSTART:" + e.Start.UTC().Format(layout),
+		"DTEND:" + e.End.UTC().Format(layout),
+		"SUMMARY:" + icsEscape(e.Summary),
+	}
+
+	if e.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+icsEscape(e.Description))
+	}
+	if e.Location != "" {
+		lines = append(lines, "LOCATION:"+icsEscape(e.Location))
+	}
+	if e.URL != "" {
+		lines = append(lines, "URL:"+e.URL)
+	}
+
+	for _, before := range e.Alarms {
+		lines = append(lines,
+			"BEGIN:VALARM",
+			"ACTION:DISPLAY",
+			fmt.Sprintf("TRIGGER:-PT%dM", int(before.Minutes())),
+			"DESCRIPTION:"+icsEscape(e.Summary),
+			"END:VALARM",
+		)
+	}
+
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(icsFold(l))
+		b.WriteString("\r\n")
+	}
+
+	return []byte(b.String())
+}
+
+var icsEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func icsEscape(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// icsFold splits content lines longer than 75 octets without breaking UTF-8
+// sequences.
+func icsFold(line string) string {
+	const limit = 75
+
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+
+	return b.String()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	DivisionLevelMunicipality = "municipality"
+	DivisionLevelDistrict     = "district"
+	DivisionLevelWard         = "ward"
+)
+
+var (
+	ErrDistrictRequired    = errors.New("district is required to look up a ward")
+	ErrNoRecallLegislators = errors.New("no recall legislators in division")
+)
+
+type DivisionNotFoundError struct {
+	Level string
+	Id    uint64
+}
+
+func (e *DivisionNotFoundError) Error() string {
+	return fmt.Sprintf("%s %d not found", e.Level, e.Id)
+}
+
+// Campaign holds everything loaded from one term's json-config directory.
+// Campaigns never share data: redistricting between terms changes both the
+// legislators and the ward-to-constituency mapping.
+type Campaign struct {
+	Term    uint64
+	BaseURL *url.URL
+	Current bool
+
+	RecallLegislators
+	RecallLegislatorMap map[uint64]RecallLegislators // uint64: ConstituencyId
+	*NameIndex
+	*CampaignIndex
+	Areas
+	Municipalities
+	PollingStations
+	RecallResults
+	WardBoundaries
+	ConstituencyGeometries *ConstituencyGeometries
+	Transitions            ScheduledTransitions
+}
+
+// LoadCampaigns reads every terms/<term> directory of the data directory. The campaign
+// of the current term keeps the root URLs; archived ones live under
+// /terms/<term>.
+func LoadCampaigns(dir string, baseURL *url.URL, currentTerm uint64, variants NameVariants) (map[uint64]*Campaign, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := map[uint64]*Campaign{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		term, err := strconv.ParseUint(e.Name(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid term directory %q: %w", e.Name(), err)
+		}
+
+		campaignURL := baseURL
+		if term != currentTerm {
+			campaignURL = baseURL.JoinPath("terms", e.Name())
+		}
+
+		c, err := ReadCampaign(filepath.Join(dir, e.Name()), term, campaignURL, variants)
+		if err != nil {
+			return nil, fmt.Errorf("term %d: %w", term, err)
+		}
+		c.Current = term == currentTerm
+
+		campaigns[term] = c
+	}
+
+	return campaigns, nil
+}
+
+func ReadCampaign(dir string, term uint64, baseURL *url.URL, variants NameVariants) (*Campaign, error) {
+	c := &Campaign{
+		Term:    term,
+		BaseURL: baseURL,
+	}
+
+	var err error
+
+	c.RecallLegislators, c.RecallLegislatorMap, err = ReadConfigRecallLegislators(dir, term, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.NameIndex, err = NewNameIndex(c.RecallLegislators, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Areas = c.RecallLegislators.ToAreas()
+
+	c.Municipalities, err = ReadConfigAdministrativeDivisions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.CampaignIndex, err = NewCampaignIndex(c.RecallLegislators, c.Municipalities)
+	if err != nil {
+		return nil, err
+	}
+
+	c.PollingStations, err = ReadConfigPollingStations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.RecallResults, err = ReadConfigRecallResults(dir, c.Municipalities)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range c.RecallLegislators {
+		l.Result = c.RecallResults[l.ConstituencyId]
+	}
+
+	c.WardBoundaries, err = ReadConfigWardBoundaries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ConstituencyGeometries, err = NewConstituencyGeometries(c.WardBoundaries, c.CampaignIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Transitions, err = ReadConfigTransitions(dir, c.RecallLegislators)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetRecallLegislator looks a legislator up by name, accepting variant
+// characters, other Unicode normalizations and configured aliases.
+func (r Campaign) GetRecallLegislator(name string) *RecallLegislator {
+	l, _ := r.Lookup(name)
+	return l
+}
+
+func (r Campaign) GetRecallLegislatorByCode(code string) *RecallLegislator {
+	return r.LegislatorByCode(code)
+}
+
+// FindRecallLegislators walks the administrative divisions down to the most
+// specific level given. It returns the child divisions when the walk stops at
+// a municipality or district, or the legislators of the ward's constituency.
+func (r Campaign) FindRecallLegislators(municipalityId uint64, districtId, wardId *uint64) (Divisions, RecallLegislators, error) {
+	if wardId != nil && districtId == nil {
+		return nil, nil, ErrDistrictRequired
+	}
+
+	municipality := r.Municipality(municipalityId)
+	if municipality == nil {
+		return nil, nil, &DivisionNotFoundError{DivisionLevelMunicipality, municipalityId}
+	}
+
+	if len(r.LegislatorsInMunicipality(municipalityId)) == 0 {
+		return nil, nil, ErrNoRecallLegislators
+	}
+
+	if districtId == nil {
+		return municipality.Divisions, nil, nil
+	}
+
+	dist, exists := municipality.Districts[*districtId]
+	if !exists {
+		return nil, nil, &DivisionNotFoundError{DivisionLevelDistrict, *districtId}
+	}
+
+	if !r.DistrictHasLegislators(dist.Id) {
+		return nil, nil, ErrNoRecallLegislators
+	}
+
+	if wardId == nil {
+		return dist.Divisions, nil, nil
+	}
+
+	ward, exists := dist.Wards[*wardId]
+	if !exists {
+		return nil, nil, &DivisionNotFoundError{DivisionLevelWard, *wardId}
+	}
+
+	rls, exists := r.RecallLegislatorMap[ward.ConstituencyId]
+	if !exists {
+		return nil, nil, ErrNoRecallLegislators
+	}
+
+	return nil, rls, nil
+}
+
+func (r Campaign) GetWard(municipalityId, districtId, wardId uint64) *Ward {
+	loc := r.Ward(wardId)
+	if loc == nil || loc.Municipality.Id != municipalityId || loc.District.Id != districtId {
+		return nil
+	}
+
+	return loc.Ward
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func FuzzFindRecallLegislators(f *testing.F) {
+	c := readTestCampaign(f)
+
+	for _, loc := range c.wards {
+		f.Add(loc.Municipality.Id, loc.District.Id, loc.Ward.Id, true, true)
+		f.Add(loc.Municipality.Id, loc.District.Id, loc.Ward.Id, true, false)
+		f.Add(loc.Municipality.Id, loc.District.Id, loc.Ward.Id, false, true)
+		break
+	}
+	f.Add(uint64(0), uint64(0), uint64(0), false, false)
+
+	f.Fuzz(func(t *testing.T, municipalityId, districtId, wardId uint64, hasDistrict, hasWard bool) {
+		var d, w *uint64
+		if hasDistrict {
+			d = &districtId
+		}
+		if hasWard {
+			w = &wardId
+		}
+
+		divisions, rls, err := c.FindRecallLegislators(municipalityId, d, w)
+
+		var notFound *DivisionNotFoundError
+		switch {
+		case err == nil:
+			if divisions == nil && len(rls) == 0 {
+				t.Fatal("no divisions or legislators without an error")
+			}
+		case errors.As(err, &notFound):
+			switch notFound.Level {
+			case DivisionLevelMunicipality, DivisionLevelDistrict, DivisionLevelWard:
+			default:
+				t.Fatalf("unknown division level %q", notFound.Level)
+			}
+		case errors.Is(err, ErrDistrictRequired), errors.Is(err, ErrNoRecallLegislators):
+		default:
+			t.Fatalf("undeclared error %T: %v", err, err)
+		}
+	})
+}
+
+func TestSearchRecallConstituency(t *testing.T) {
+	c := readTestCampaign(t)
+	ctrl := NewController(&Config{
+		AppBaseURL: c.BaseURL,
+		RecallTerm: c.Term,
+		Campaigns:  map[uint64]*Campaign{c.Term: c},
+		Campaign:   c,
+	}, nil)
+
+	var found *WardLocation
+	for _, loc := range c.wards {
+		if len(c.RecallLegislatorMap[loc.Ward.ConstituencyId]) > 0 {
+			found = loc
+			break
+		}
+	}
+	if found == nil {
+		t.Fatal("no wards with recall legislators")
+	}
+
+	var idle *Municipality // municipality without recall legislators
+	for id, m := range c.municipalities {
+		if len(c.LegislatorsInMunicipality(id)) == 0 {
+			idle = m
+			break
+		}
+	}
+	if idle == nil {
+		t.Fatal("no municipalities without recall legislators")
+	}
+
+	m := strconv.FormatUint(found.Municipality.Id, 10)
+	d := strconv.FormatUint(found.District.Id, 10)
+	w := strconv.FormatUint(found.Ward.Id, 10)
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+	}{
+		{"ward", url.Values{"municipality": {m}, "district": {d}, "ward": {w}}, http.StatusOK},
+		{"district", url.Values{"municipality": {m}, "district": {d}}, http.StatusOK},
+		{"municipality", url.Values{"municipality": {m}}, http.StatusOK},
+		{"term", url.Values{"term": {strconv.FormatUint(c.Term, 10)}, "municipality": {m}}, http.StatusOK},
+		{"missing municipality", url.Values{}, http.StatusBadRequest},
+		{"invalid municipality", url.Values{"municipality": {"x"}}, http.StatusBadRequest},
+		{"invalid district", url.Values{"municipality": {m}, "district": {"x"}}, http.StatusBadRequest},
+		{"invalid ward", url.Values{"municipality": {m}, "district": {d}, "ward": {"x"}}, http.StatusBadRequest},
+		{"ward without district", url.Values{"municipality": {m}, "ward": {w}}, http.StatusBadRequest},
+		{"unknown term", url.Values{"term": {"1"}, "municipality": {m}}, http.StatusBadRequest},
+		{"unknown municipality", url.Values{"municipality": {"999999"}}, http.StatusNotFound},
+		{"no recall legislators", url.Values{"municipality": {strconv.FormatUint(idle.Id, 10)}}, http.StatusNotFound},
+		{"unknown district", url.Values{"municipality": {m}, "district": {"999999"}}, http.StatusNotFound},
+		{"unknown ward", url.Values{"municipality": {m}, "district": {d}, "ward": {"999999"}}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/apis/constituencies?"+tt.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+
+			ctrl.SearchRecallConstituency(rec, r)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
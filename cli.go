@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Commands run instead of the server when the binary is given arguments.
+//
+//	recall-2025 admin-account <username>
+//	recall-2025 audit [-config <file>] [-term <term>] [-constituency <code>] [-limit <n>] [-json]
+func runCommand(args []string) int {
+	switch args[0] {
+	case "admin-account":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: recall-2025 admin-account <username>")
+			return 2
+		}
+		return runAdminAccount(args[1])
+	case "audit":
+		return runAudit(args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+	return 2
+}
+
+// runAdminAccount reads a password from stdin and prints an entry for the
+// admin accounts file, with the TOTP URI to enroll in an authenticator app on
+// stderr.
+func runAdminAccount(username string) int {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if len(password) < 12 {
+		fmt.Fprintln(os.Stderr, "password must be at least 12 characters")
+		return 1
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	data, err := json.MarshalIndent(&AdminAccount{
+		Username:     username,
+		PasswordHash: hash,
+		TOTPSecret:   secret,
+	}, "", "\t")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, TOTPURI("recall-2025", username, secret))
+	fmt.Println(string(data))
+	return 0
+}
+
+// runAudit prints the history of a campaign from the audit log, latest
+// first. The log is found through the settings of the server: --config,
+// APP_AUDIT_LOG or --audit-log.
+func runAudit(args []string) int {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	sf := newSettingsFlags(flags)
+	logPath := flags.String("log", "", "audit log `path`, overriding the audit-log setting")
+	term := flags.Uint64("term", 0, "only show `term`")
+	code := flags.String("constituency", "", "only show the constituency with `code`")
+	limit := flags.Int("limit", 0, "show at most `n` entries")
+	asJSON := flags.Bool("json", false, "print the entries as JSON Lines")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "settings error:", err)
+		return 2
+	}
+	if *logPath != "" {
+		s.AuditLog = *logPath
+	}
+
+	entries, err := NewAuditLog(s.AuditLog).Query(AuditQuery{Term: *term, ConstituencyCode: *code, Limit: *limit})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, e := range entries {
+		if *asJSON {
+			line, _ := json.Marshal(e)
+			fmt.Println(string(line))
+			continue
+		}
+
+		fmt.Printf("%s  %s (%s)  %d/%s %s\n", e.Time.Format(time.DateTime), e.Actor, e.Source, e.Term, e.ConstituencyCode, e.PoliticianName)
+		for _, c := range e.Changes {
+			fmt.Printf("\t%s: %s -> %s\n", c.Field, rawOrNull(c.From), rawOrNull(c.To))
+		}
+	}
+
+	return 0
+}
+
+func rawOrNull(v json.RawMessage) string {
+	if len(v) == 0 {
+		return "null"
+	}
+
+	return string(v)
+}
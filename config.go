@@ -7,84 +7,92 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
-	"strings"
 	"time"
 )
 
 const (
 	RecallStatusOngoing = "ONGOING"
+	RecallStatusAborted = "ABORTED"
 	RecallStatusSuccess = "SUCCESS"
 	RecallStatusFailed  = "FAILED"
 )
 
+// Only production caches templates and responses. stage reloads templates on
+// every request like dev, without the form layout editor.
 const (
 	AppEnvProduction = "production"
+	AppEnvStage      = "stage"
 	AppEnvDev        = "dev"
 )
 
+var AppEnvs = []string{AppEnvDev, AppEnvStage, AppEnvProduction}
+
 type Config struct {
 	AppEnv             string
-	AppHostname        string
-	AppPath            string
-	AppPort            string
-	AppTrustedProxies  []string
 	AppBaseURL         *url.URL
+	AppTrustedProxies  []string
+	DataDir            string
+	TemplatesDir       string
 	TurnstileSiteKey   string
 	TurnstileSecretKey string
 	DisallowPaths      []string
+	AdminAccountsPath  string // admin console is disabled when empty
+	AuditLogPath       string
 
 	RecallTerm uint64
-	RecallLegislators
-	RecallLegislatorMap map[uint64]RecallLegislators // uint64: ConstituencyId
-	Areas
-	Municipalities
+	Campaigns  map[uint64]*Campaign // uint64: Term
+	*Campaign                       // campaign of RecallTerm
+
+	Catalogs    Catalogs
+	FormLayouts FormLayouts
 }
 
-func LoadConfig() (*Config, error) {
+// LoadConfig loads the data directory with the given settings.
+func LoadConfig(s *Settings) (*Config, error) {
 	cfg := &Config{
-		AppEnv:             os.Getenv("APP_ENV"),
-		AppHostname:        os.Getenv("APP_HOSTNAME"),
-		AppPath:            os.Getenv("APP_PATH"),
-		AppPort:            os.Getenv("APP_PORT"),
-		AppTrustedProxies:  strings.Split(strings.ReplaceAll(os.Getenv("APP_TRUSTED_PROXIES"), " ", ""), ","),
-		TurnstileSiteKey:   os.Getenv("TURNSTILE_SITE_KEY"),
-		TurnstileSecretKey: os.Getenv("TURNSTILE_SECRET_KEY"),
-		DisallowPaths:      []string{"/health/", "/apis/", "/assets/"},
-		RecallTerm:         11,
+		AppEnv:             s.Env,
+		AppTrustedProxies:  s.TrustedProxies,
+		DataDir:            s.DataDir,
+		TemplatesDir:       s.TemplatesDir,
+		TurnstileSiteKey:   s.TurnstileSiteKey,
+		TurnstileSecretKey: s.TurnstileSecretKey,
+		DisallowPaths:      s.DisallowPaths,
+		AdminAccountsPath:  s.AdminAccounts,
+		AuditLogPath:       s.AuditLog,
+		RecallTerm:         s.RecallTerm,
 	}
 
-	if !strings.HasPrefix(cfg.AppPath, "/") {
-		cfg.AppPath = "/" + cfg.AppPath
-	}
+	var err error
 
-	scheme := "https"
-	if strings.HasPrefix(cfg.AppHostname, "localhost") {
-		scheme = "http"
+	cfg.AppBaseURL, err = s.PublicURL()
+	if err != nil {
+		return nil, err
 	}
 
-	rootPath := ""
-	if cfg.AppPath == "/" {
-		rootPath = cfg.AppHostname
-	} else {
-		rootPath = cfg.AppHostname + cfg.AppPath
+	variants, err := ReadConfigNameVariants(cfg.DataDir)
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
-
-	cfg.AppBaseURL, err = url.ParseRequestURI(scheme + "://" + rootPath)
+	cfg.Campaigns, err = LoadCampaigns(cfg.DataPath(JSONConfigTermsDir), cfg.AppBaseURL, cfg.RecallTerm, variants)
 	if err != nil {
 		return nil, err
 	}
 
-	cfg.RecallLegislators, cfg.RecallLegislatorMap, err = ReadConfigRecallLegislators(cfg.AppBaseURL)
+	cfg.Campaign = cfg.Campaigns[cfg.RecallTerm]
+	if cfg.Campaign == nil {
+		return nil, fmt.Errorf("campaign of term %d not found in %s", cfg.RecallTerm, cfg.DataPath(JSONConfigTermsDir))
+	}
+
+	cfg.Catalogs, err = ReadConfigCatalogs(cfg.DataPath(JSONConfigLocalesDir))
 	if err != nil {
 		return nil, err
 	}
 
-	cfg.Areas = cfg.RecallLegislators.ToAreas()
-
-	cfg.Municipalities, err = ReadConfigAdministrativeDivisions()
+	cfg.FormLayouts, err = ReadConfigFormLayouts(cfg.DataPath(JSONConfigFormLayoutsDir))
 	if err != nil {
 		return nil, err
 	}
@@ -92,49 +100,32 @@ func LoadConfig() (*Config, error) {
 	return cfg, nil
 }
 
-func (r Config) GetRecallLegislator(name string) *RecallLegislator {
-	for _, row := range r.RecallLegislators {
-		if row.PoliticianName == name {
-			return row
-		}
-	}
-
-	return nil
+// DataPath joins elem to the data directory.
+func (r Config) DataPath(elem ...string) string {
+	return filepath.Join(append([]string{r.DataDir}, elem...)...)
 }
 
-func (r Config) HasRecallLegislators(municipalityId uint64, districtId, wardId *uint64) (bool, Divisions, RecallLegislators) {
-	if !r.RecallLegislators.HasLegislatorInMunicipality(municipalityId) {
-		return false, nil, nil
-	}
-
-	municipality := r.Municipalities[municipalityId]
-	if districtId == nil {
-		return true, municipality.Divisions, nil
-	}
+// GetCampaign returns the campaign of the given term, or nil if it is not
+// loaded.
+func (r Config) GetCampaign(term uint64) *Campaign {
+	return r.Campaigns[term]
+}
 
-	dist := municipality.Districts[*districtId]
-	matched := false
-	for _, w := range dist.Wards {
-		if _, exists := r.RecallLegislatorMap[w.ConstituencyId]; exists {
-			matched = true
-			break
+// ArchivedCampaigns returns every campaign except the current one, latest
+// term first.
+func (r Config) ArchivedCampaigns() []*Campaign {
+	cs := []*Campaign{}
+	for term, c := range r.Campaigns {
+		if term != r.RecallTerm {
+			cs = append(cs, c)
 		}
 	}
 
-	if !matched {
-		return false, nil, nil
-	}
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].Term > cs[j].Term
+	})
 
-	if wardId == nil {
-		return true, dist.Divisions, nil
-	}
-
-	constituencyId := dist.Wards[*wardId].ConstituencyId
-	if rls, exists := r.RecallLegislatorMap[constituencyId]; exists {
-		return true, nil, rls
-	}
-
-	return false, nil, nil
+	return cs
 }
 
 func (r Config) VerifyTurnstileToken(token string) (bool, error) {
@@ -175,13 +166,15 @@ type TurnstileSiteverifyResponse struct {
 }
 
 const (
-	JSONConfigRecallLegislators       = "json-config/recall-legislators.json"
-	JSONConfigAdministrativeDivisions = "json-config/administrative-divisions.json"
+	JSONConfigTermsDir                = "terms"
+	JSONConfigRecallLegislators       = "recall-legislators.json"
+	JSONConfigAdministrativeDivisions = "administrative-divisions.json"
+	JSONConfigPollingStations         = "polling-stations.json"
 )
 
 // config: recall-legislator
-func ReadConfigRecallLegislators(baseURL *url.URL) (RecallLegislators, map[uint64]RecallLegislators, error) {
-	file, err := os.Open(JSONConfigRecallLegislators)
+func ReadConfigRecallLegislators(dir string, term uint64, baseURL *url.URL) (RecallLegislators, map[uint64]RecallLegislators, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigRecallLegislators))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -195,15 +188,36 @@ func ReadConfigRecallLegislators(baseURL *url.URL) (RecallLegislators, map[uint6
 	}
 
 	rlmap := map[uint64]RecallLegislators{}
+	codes := map[string]bool{}
 	for _, r := range rows {
-		r.ParticipateURL = baseURL.JoinPath("legislators", r.PoliticianName)
+		if r.Term != term {
+			return nil, nil, fmt.Errorf("recall-legislators: %s belongs to term %d, not %d", r.PoliticianName, r.Term, term)
+		}
+
+		if !constituencyCodePattern.MatchString(r.ConstituencyCode) {
+			return nil, nil, fmt.Errorf("recall-legislators: invalid constituencyCode %q of %s", r.ConstituencyCode, r.PoliticianName)
+		}
+		if codes[r.ConstituencyCode] {
+			return nil, nil, fmt.Errorf("recall-legislators: duplicated constituencyCode %q", r.ConstituencyCode)
+		}
+		codes[r.ConstituencyCode] = true
+
+		r.ParticipateURL = baseURL.JoinPath("c", r.ConstituencyCode)
 		r.ParticipateURLString = r.ParticipateURL.String()
-		if r.SafetyCutoffDate != nil && *r.SafetyCutoffDate != "" {
-			t, err := time.Parse("2006-01-02", *r.SafetyCutoffDate)
-			if err != nil {
-				return nil, nil, err
+		for _, d := range []struct {
+			field string
+			date  *string
+		}{
+			{"safetyCutoffDate", r.SafetyCutoffDate},
+			{"votingDate", r.VotingDate},
+			{"byElectionDate", r.ByElectionDate},
+		} {
+			if d.date == nil || *d.date == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", *d.date); err != nil {
+				return nil, nil, fmt.Errorf("recall-legislators: invalid %s %q of %s", d.field, *d.date, r.PoliticianName)
 			}
-			r.SafetyCutoffDateStr = fmt.Sprintf("%d 月 %d 日", t.Month(), t.Day())
 		}
 
 		if _, exists := rlmap[r.ConstituencyId]; !exists {
@@ -216,96 +230,179 @@ func ReadConfigRecallLegislators(baseURL *url.URL) (RecallLegislators, map[uint6
 	return rows, rlmap, nil
 }
 
+var constituencyCodePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 type RecallLegislators []*RecallLegislator
 
-func (rs RecallLegislators) HasLegislatorInMunicipality(municipalityId uint64) bool {
-	for _, r := range rs {
-		if r.MunicipalityId == municipalityId {
-			return true
-		}
-	}
+type RecallLegislator struct {
+	ConstituencyId        uint64        `json:"constituencyId"`
+	MunicipalityId        uint64        `json:"municipalityId"`
+	Term                  uint64        `json:"term"`
+	MunicipalityName      string        `json:"municipalityName"`
+	ConstituencyNum       uint64        `json:"constituencyNum"`
+	PoliticianName        string        `json:"politicianName"`
+	Aliases               []string      `json:"aliases,omitempty"`
+	RecallStage           uint64        `json:"recallStage"`
+	RecallStatus          string        `json:"recallStatus"`
+	FormDeployed          bool          `json:"formDeployed"`
+	CsoURL                string        `json:"csoURL"`
+	CalendarURL           string        `json:"calendarURL"`
+	HasCalendarMaintainer bool          `json:"hasCalendarMaintainer"`
+	VotingDate            *string       `json:"votingDate"`
+	VotingEventURL        *string       `json:"votingEventURL"`
+	ByElectionDate        *string       `json:"byElectionDate"`
+	ByElectionEventURL    *string       `json:"byElectionEventURL"`
+	SafetyCutoffDate      *string       `json:"safetyCutoffDate"`
+	ConstituencyName      string        `json:"constituencyName"`
+	ConstituencyCode      string        `json:"constituencyCode"`
+	ParticipateURL        *url.URL      `json:"-"`
+	ParticipateURLString  string        `json:"participateURL"`
+	Result                *RecallResult `json:"result,omitempty"`
+}
 
-	return false
+// MarshalJSON adds the days left, counted when called, for the pages that
+// render legislators in the browser.
+func (r *RecallLegislator) MarshalJSON() ([]byte, error) {
+	type recallLegislator RecallLegislator
+	now := time.Now().In(Location)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(struct {
+		*recallLegislator
+		DaysLeft        int        `json:"daysLeft"`
+		SafetyCutoffEnd *time.Time `json:"safetyCutoffEnd,omitempty"`
+		VotingDaysLeft  int        `json:"votingDaysLeft"`
+	}{
+		recallLegislator: (*recallLegislator)(r),
+		DaysLeft:         r.daysLeftAt(now),
+		SafetyCutoffEnd:  r.safetyCutoffEnd(now.Location()),
+		VotingDaysLeft:   daysUntil(r.VotingDate, now),
+	})
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), err
+}
+
+// DaysLeft and VotingDaysLeft count the calendar days from today in Taiwan to
+// the safety cutoff and voting dates: 1 the day before, 0 on the day and
+// negative after. They are counted when called, so pages turn at midnight
+// rather than when the config was loaded.
+func (r RecallLegislator) DaysLeft() int {
+	return r.daysLeftAt(time.Now().In(Location))
 }
 
-func (rs *RecallLegislators) CalcDaysLeft(now time.Time) {
-	for _, r := range *rs {
-		r.CalcDaysLeft(now)
+func (r RecallLegislator) VotingDaysLeft() int {
+	return daysUntil(r.VotingDate, time.Now().In(Location))
+}
+
+func (r RecallLegislator) daysLeftAt(now time.Time) int {
+	return daysUntil(r.SafetyCutoffDate, now)
+}
+
+// HoursLeft and MinutesLeft count down to the end of the safety cutoff date
+// on its last day.
+func (r RecallLegislator) HoursLeft() int {
+	return int(r.timeLeftAt(time.Now().In(Location)) / time.Hour)
+}
+
+func (r RecallLegislator) MinutesLeft() int {
+	return int(r.timeLeftAt(time.Now().In(Location)) % time.Hour / time.Minute)
+}
+
+func (r RecallLegislator) timeLeftAt(now time.Time) time.Duration {
+	end := r.safetyCutoffEnd(now.Location())
+	if !r.isLastDayAt(now) || end == nil {
+		return 0
 	}
+
+	left := end.Sub(now)
+	if left < 0 {
+		return 0
+	}
+
+	return left
 }
 
-type RecallLegislator struct {
-	ConstituencyId        uint64   `json:"constituencyId"`
-	MunicipalityId        uint64   `json:"municipalityId"`
-	Term                  uint64   `json:"term"`
-	MunicipalityName      string   `json:"municipalityName"`
-	ConstituencyNum       uint64   `json:"constituencyNum"`
-	PoliticianName        string   `json:"politicianName"`
-	RecallStage           uint64   `json:"recallStage"`
-	RecallStatus          string   `json:"recallStatus"`
-	FormDeployed          bool     `json:"formDeployed"`
-	CsoURL                string   `json:"csoURL"`
-	CalendarURL           string   `json:"calendarURL"`
-	HasCalendarMaintainer bool     `json:"hasCalendarMaintainer"`
-	VotingDate            *string  `json:"votingDate"`
-	VotingEventURL        *string  `json:"votingEventURL"`
-	ByElectionDate        *string  `json:"byElectionDate"`
-	ByElectionEventURL    *string  `json:"byElectionEventURL"`
-	SafetyCutoffDate      *string  `json:"safetyCutoffDate"`
-	ConstituencyName      string   `json:"constituencyName"`
-	ParticipateURL        *url.URL `json:"-"`
-	ParticipateURLString  string   `json:"participateURL"`
-	DaysLeft              int      `json:"daysLeft"`
-	SafetyCutoffDateStr   string   `json:"safetyCutoffDateStr"`
-}
-
-func (r *RecallLegislator) CalcDaysLeft(now time.Time) {
+// safetyCutoffEnd returns the instant the safety cutoff date ends in loc,
+// which pages count down to on the last day, or nil without a date.
+func (r RecallLegislator) safetyCutoffEnd(loc *time.Location) *time.Time {
 	if r.SafetyCutoffDate == nil || *r.SafetyCutoffDate == "" {
-		r.DaysLeft = 0
-		return
+		return nil
+	}
+
+	end := endOfDate(*r.SafetyCutoffDate, loc)
+	return &end
+}
+
+// daysUntil counts calendar days rather than 24-hour periods, so it changes
+// at midnight and does not depend on DST. Dates are validated when the config
+// is loaded.
+func daysUntil(date *string, now time.Time) int {
+	if date == nil || *date == "" {
+		return 0
 	}
 
-	cutoff, err := time.Parse("2006-01-02", *r.SafetyCutoffDate)
+	t, err := time.Parse("2006-01-02", *date)
 	if err != nil {
-		r.DaysLeft = 0
-		return
+		return 0
 	}
 
-	r.DaysLeft = int(cutoff.Sub(now).Hours() / 24)
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	return int(t.Sub(today).Hours()) / 24
 }
 
-func (r RecallLegislator) IsPetitioning() bool {
-	if r.RecallStage == 1 || r.RecallStage == 2 {
+// endOfDate returns the midnight that ends date in loc.
+func endOfDate(date string, loc *time.Location) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t.AddDate(0, 0, 1)
+}
+
+// IsLastDay reports whether today in Taiwan is the safety cutoff date.
+func (r RecallLegislator) IsLastDay() bool {
+	return r.isLastDayAt(time.Now().In(Location))
+}
+
+func (r RecallLegislator) isLastDayAt(now time.Time) bool {
+	return r.SafetyCutoffDate != nil && *r.SafetyCutoffDate != "" && r.daysLeftAt(now) == 0
+}
+
+// IsVoting reports whether the recall has passed both petition stages and is
+// waiting for (or holding) the vote.
+func (r RecallLegislator) IsVoting() bool {
+	if r.RecallStage == 3 || r.RecallStage == 4 {
 		return true
 	}
 
 	return false
 }
 
-func (r RecallLegislator) GetTmplFilename() string {
-	switch r.RecallStage {
-	case 1, 2:
-		return fmt.Sprintf("stage-%d-%s.html", r.RecallStage, r.PoliticianName)
+func (r RecallLegislator) IsPetitioning() bool {
+	if r.RecallStage == 1 || r.RecallStage == 2 {
+		return true
 	}
 
-	return ""
+	return false
 }
 
 func (rs RecallLegislators) ToAreas() Areas {
 	areas := Areas{}
+	byMunicipality := map[uint64]*Area{}
 	for _, r := range rs {
-		matched := false
-		for _, a := range areas {
-			if a.MunicipalityId == r.MunicipalityId {
-				matched = true
-				a.RecallLegislators = append(a.RecallLegislators, r)
-				break
-			}
+		if a, exists := byMunicipality[r.MunicipalityId]; exists {
+			a.RecallLegislators = append(a.RecallLegislators, r)
+			continue
 		}
 
-		if !matched {
-			areas = append(areas, &Area{r.MunicipalityId, &r.MunicipalityName, RecallLegislators{r}})
-		}
+		a := &Area{r.MunicipalityId, &r.MunicipalityName, RecallLegislators{r}}
+		byMunicipality[r.MunicipalityId] = a
+		areas = append(areas, a)
 	}
 
 	return areas
@@ -320,8 +417,8 @@ type Area struct {
 }
 
 // config: administrative-divisions
-func ReadConfigAdministrativeDivisions() (Municipalities, error) {
-	file, err := os.Open(JSONConfigAdministrativeDivisions)
+func ReadConfigAdministrativeDivisions(dir string) (Municipalities, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigAdministrativeDivisions))
 	if err != nil {
 		return nil, err
 	}
@@ -382,3 +479,30 @@ type Ward struct {
 	*Division
 	ConstituencyId uint64 `json:"cid"`
 }
+
+// config: polling-stations
+func ReadConfigPollingStations(dir string) (PollingStations, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigPollingStations))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows := PollingStations{}
+
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+type PollingStations map[uint64][]*PollingStation // uint64: WardId
+
+type PollingStation struct {
+	Num           uint64 `json:"num"`
+	Name          string `json:"name"`
+	Address       string `json:"address"`
+	Neighborhoods string `json:"neighborhoods"`
+}
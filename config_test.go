@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecallLegislatorDaysLeft(t *testing.T) {
+	cutoff := "2025-05-03"
+	l := RecallLegislator{SafetyCutoffDate: &cutoff}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		days     int
+		lastDay  bool
+		timeLeft time.Duration
+	}{
+		{"the day before", time.Date(2025, 5, 2, 23, 59, 0, 0, Location), 1, false, 0},
+		{"00:30 on the cutoff date", time.Date(2025, 5, 3, 0, 30, 0, 0, Location), 0, true, 23*time.Hour + 30*time.Minute},
+		{"the last minute", time.Date(2025, 5, 3, 23, 59, 0, 0, Location), 0, true, time.Minute},
+		{"the day after", time.Date(2025, 5, 4, 0, 0, 0, 0, Location), -1, false, 0},
+		{"00:30 in Taiwan read in UTC", time.Date(2025, 5, 2, 16, 30, 0, 0, time.UTC).In(Location), 0, true, 23*time.Hour + 30*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.daysLeftAt(tt.now); got != tt.days {
+				t.Errorf("daysLeftAt(%v) = %d, want %d", tt.now, got, tt.days)
+			}
+			if got := l.isLastDayAt(tt.now); got != tt.lastDay {
+				t.Errorf("isLastDayAt(%v) = %v, want %v", tt.now, got, tt.lastDay)
+			}
+			if got := l.timeLeftAt(tt.now); got != tt.timeLeft {
+				t.Errorf("timeLeftAt(%v) = %v, want %v", tt.now, got, tt.timeLeft)
+			}
+		})
+	}
+}
+
+func TestRecallLegislatorMarshalJSON(t *testing.T) {
+	cutoff := "2099-01-01"
+	l := &RecallLegislator{PoliticianName: "王小明", SafetyCutoffDate: &cutoff, CsoURL: "https://example.tw/?a=1&b=2"}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["politicianName"] != l.PoliticianName || got["csoURL"] != l.CsoURL {
+		t.Errorf("fields of the legislator not kept: %s", data)
+	}
+	if days, ok := got["daysLeft"].(float64); !ok || days <= 0 {
+		t.Errorf("daysLeft = %v, want the days until %s", got["daysLeft"], cutoff)
+	}
+	if got["safetyCutoffEnd"] != "2099-01-02T00:00:00+08:00" {
+		t.Errorf("safetyCutoffEnd = %v, want the end of %s in Taiwan", got["safetyCutoffEnd"], cutoff)
+	}
+}
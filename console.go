@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The admin console lets campaign maintainers change the status of a recall
+// without a deploy. It is only served when APP_ADMIN_ACCOUNTS names an
+// accounts file; logins need the password and a TOTP code.
+
+const (
+	MsgAdminLoginFailed = "admin.console.login_failed"
+	MsgAdminLoginLocked = "admin.console.login_locked"
+	MsgAdminLoginBusy   = "admin.console.login_busy"
+)
+
+// AdminConsole serves the console from a snapshot; edits go through app so
+// they swap in a new one.
+type AdminConsole struct {
+	*Controller
+	app *App
+}
+
+func (c *AdminConsole) enabled(w http.ResponseWriter, r *http.Request) bool {
+	if c.app.Accounts == nil {
+		c.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, c.AppBaseURL, c.AppBaseURL))
+		return false
+	}
+
+	return true
+}
+
+func (c *AdminConsole) session(r *http.Request) (string, *AdminSession) {
+	cookie, err := r.Cookie(AdminSessionCookieName)
+	if err != nil {
+		return "", nil
+	}
+
+	return cookie.Value, c.app.Sessions.Get(cookie.Value, time.Now())
+}
+
+// sameOrigin rejects cross-site requests that change state, on top of the
+// SameSite cookie, for browsers that send neither.
+func (c *AdminConsole) sameOrigin(r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return origin == c.AppBaseURL.Scheme+"://"+c.AppBaseURL.Host
+	}
+
+	site := r.Header.Get("Sec-Fetch-Site")
+	return site == "" || site == "same-origin"
+}
+
+func (c *AdminConsole) setSessionCookie(w http.ResponseWriter, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AdminSessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.AppBaseURL.Scheme == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Home lists the legislators of a term with their status for editing
+// (GET /admin?term=<term>).
+func (c *AdminConsole) Home(w http.ResponseWriter, r *http.Request) {
+	if !c.enabled(w, r) {
+		return
+	}
+
+	_, session := c.session(r)
+	if session == nil {
+		http.Redirect(w, r, c.AppBaseURL.JoinPath("admin", "login").String(), http.StatusSeeOther)
+		return
+	}
+
+	campaign := c.Campaign
+	if t := r.URL.Query().Get("term"); t != "" {
+		term, err := strconv.ParseUint(t, 10, 64)
+		if err != nil || c.GetCampaign(term) == nil {
+			c.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, c.AppBaseURL, c.AppBaseURL.JoinPath("admin")))
+			return
+		}
+		campaign = c.GetCampaign(term)
+	}
+
+	terms := []uint64{}
+	for term := range c.Campaigns {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i] > terms[j]
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	c.renderTemplate(w, r, "admin.html", map[string]interface{}{
+		"BaseURL":     c.AppBaseURL.String(),
+		"Username":    session.Username,
+		"Term":        campaign.Term,
+		"Terms":       terms,
+		"Legislators": campaign.RecallLegislators,
+		"Stages":      RecallStages,
+		"Statuses":    RecallStatuses,
+		"UpdateURL":   c.AppBaseURL.JoinPath("apis", "admin", "legislators").String(),
+	})
+}
+
+// Login shows the login form (GET /admin/login) and logs in with a username,
+// password and TOTP code (POST /admin/login).
+func (c *AdminConsole) Login(w http.ResponseWriter, r *http.Request) {
+	if !c.enabled(w, r) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch r.Method {
+	case http.MethodGet:
+		c.renderTemplate(w, r, "admin-login.html", map[string]interface{}{
+			"BaseURL": c.AppBaseURL.String(),
+		})
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !c.sameOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	username := r.PostFormValue("username")
+	now := time.Now()
+
+	fail := func(status int, message string) {
+		c.renderTemplateStatus(w, r, status, "admin-login.html", map[string]interface{}{
+			"BaseURL":  c.AppBaseURL.String(),
+			"Username": username,
+			"Error":    message,
+		})
+	}
+
+	if c.app.Sessions.Locked(username, now) {
+		fail(http.StatusTooManyRequests, MsgAdminLoginLocked)
+		return
+	}
+
+	if !c.app.Sessions.BeginLogin() {
+		w.Header().Set("Retry-After", "1")
+		fail(http.StatusServiceUnavailable, MsgAdminLoginBusy)
+		return
+	}
+	account, ok := c.app.Accounts.Authenticate(username, r.PostFormValue("password"), r.PostFormValue("totp"), now)
+	c.app.Sessions.EndLogin()
+	if !ok {
+		if _, exists := c.app.Accounts[username]; exists {
+			c.app.Sessions.Fail(username, now)
+		}
+		log.Printf("admin: login failed for %q from %s", username, r.RemoteAddr)
+		fail(http.StatusUnauthorized, MsgAdminLoginFailed)
+		return
+	}
+
+	token, err := c.app.Sessions.Create(account.Username, now)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("admin: %s logged in from %s", account.Username, r.RemoteAddr)
+	c.setSessionCookie(w, token, int(AdminSessionTTL/time.Second))
+	http.Redirect(w, r, c.AppBaseURL.JoinPath("admin").String(), http.StatusSeeOther)
+}
+
+// Logout ends the session (POST /admin/logout).
+func (c *AdminConsole) Logout(w http.ResponseWriter, r *http.Request) {
+	if !c.enabled(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !c.sameOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if token, _ := c.session(r); token != "" {
+		c.app.Sessions.Delete(token)
+	}
+
+	c.setSessionCookie(w, "", -1)
+	http.Redirect(w, r, c.AppBaseURL.JoinPath("admin", "login").String(), http.StatusSeeOther)
+}
+
+type RespUpdateLegislator struct {
+	Message string                  `json:"message"`
+	Result  *ResultUpdateLegislator `json:"result,omitempty"`
+}
+
+type ResultUpdateLegislator struct {
+	Changes []*AuditChange `json:"changes"`
+}
+
+// UpdateLegislator edits the status of a legislator, writes it to
+// recall-legislators.json and applies it to the live site
+// (PUT /apis/admin/legislators/<constituencyCode>?term=<term>).
+func (c *AdminConsole) UpdateLegislator(w http.ResponseWriter, r *http.Request) {
+	if c.app.Accounts == nil {
+		writeJSON(w, http.StatusNotFound, RespUpdateLegislator{Message: http.StatusText(http.StatusNotFound)})
+		return
+	}
+
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", http.MethodPut)
+		writeJSON(w, http.StatusMethodNotAllowed, RespUpdateLegislator{Message: http.StatusText(http.StatusMethodNotAllowed)})
+		return
+	}
+
+	_, session := c.session(r)
+	if session == nil {
+		writeJSON(w, http.StatusUnauthorized, RespUpdateLegislator{Message: http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	if !c.sameOrigin(r) {
+		writeJSON(w, http.StatusForbidden, RespUpdateLegislator{Message: http.StatusText(http.StatusForbidden)})
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, RespUpdateLegislator{Message: http.StatusText(http.StatusUnsupportedMediaType)})
+		return
+	}
+
+	code := strings.TrimPrefix(r.URL.Path, "/apis/admin/legislators/")
+	term := c.RecallTerm
+	if t := r.URL.Query().Get("term"); t != "" {
+		var err error
+		term, err = strconv.ParseUint(t, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+			return
+		}
+	}
+
+	edit := &RecallLegislatorEdit{}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(edit); err != nil {
+		writeJSON(w, http.StatusBadRequest, RespUpdateLegislator{Message: err.Error()})
+		return
+	}
+
+	if err := edit.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, RespUpdateLegislator{Message: err.Error()})
+		return
+	}
+
+	if c.GetCampaign(term) == nil {
+		writeJSON(w, http.StatusNotFound, RespUpdateLegislator{Message: http.StatusText(http.StatusNotFound)})
+		return
+	}
+
+	entry, err := c.app.UpdateRecallLegislator(term, code, edit, session.Username)
+	if errors.Is(err, ErrRecallLegislatorNotFound) {
+		writeJSON(w, http.StatusNotFound, RespUpdateLegislator{Message: err.Error()})
+		return
+	}
+	if err != nil && entry == nil {
+		log.Printf("admin: %s: update %d/%s: %v", session.Username, term, code, err)
+		writeJSON(w, http.StatusInternalServerError, RespUpdateLegislator{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		// The change is live; only its audit entry is missing.
+		log.Printf("admin: %s: update %d/%s: %v", session.Username, term, code, err)
+	}
+
+	writeJSON(w, http.StatusOK, RespUpdateLegislator{
+		Message: http.StatusText(http.StatusOK),
+		Result:  &ResultUpdateLegislator{Changes: entry.Changes},
+	})
+}
+
+type RespAuditLog struct {
+	Message string          `json:"message"`
+	Result  *ResultAuditLog `json:"result,omitempty"`
+}
+
+type ResultAuditLog struct {
+	Entries []*AuditEntry `json:"entries"`
+}
+
+// AuditLog returns the history of a campaign, latest first
+// (GET /apis/admin/audit?term=<term>&constituency=<code>&limit=<n>).
+func (c *AdminConsole) AuditLog(w http.ResponseWriter, r *http.Request) {
+	if c.app.Accounts == nil {
+		writeJSON(w, http.StatusNotFound, RespAuditLog{Message: http.StatusText(http.StatusNotFound)})
+		return
+	}
+
+	if _, session := c.session(r); session == nil {
+		writeJSON(w, http.StatusUnauthorized, RespAuditLog{Message: http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	q := AuditQuery{
+		Term:             c.RecallTerm,
+		ConstituencyCode: r.URL.Query().Get("constituency"),
+		Limit:            100,
+	}
+
+	if t := r.URL.Query().Get("term"); t != "" {
+		term, err := strconv.ParseUint(t, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+			return
+		}
+		q.Term = term
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "limit error"})
+			return
+		}
+		q.Limit = limit
+	}
+
+	entries, err := c.app.Audit.Query(q)
+	if err != nil {
+		log.Printf("admin: audit: %v", err)
+		writeJSON(w, http.StatusInternalServerError, RespAuditLog{Message: http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, RespAuditLog{
+		Message: http.StatusText(http.StatusOK),
+		Result:  &ResultAuditLog{Entries: entries},
+	})
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,41 +26,62 @@ const (
 
 type Controller struct {
 	*Config
-	Templates *template.Template
+	Templates *TemplateRegistry
+
+	formLayoutsMu sync.RWMutex
 }
 
-func NewController(cfg *Config, tmpl *template.Template) *Controller {
+func NewController(cfg *Config, tmpls *TemplateRegistry) *Controller {
 	return &Controller{
 		Config:    cfg,
-		Templates: tmpl,
+		Templates: tmpls,
 	}
 }
 
-func (ctrl *Controller) CalcDaysLeft() error {
-	loc, err := time.LoadLocation("Asia/Taipei")
-	if err != nil {
-		return err
+func (ctrl *Controller) Home(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		ctrl.CampaignHome(w, r, ctrl.Campaign)
+	} else {
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
 	}
+}
 
-	now := time.Now().In(loc)
-	ctrl.Config.CalcDaysLeft(now)
-	return nil
+func (ctrl *Controller) CampaignHome(w http.ResponseWriter, r *http.Request, c *Campaign) {
+	ctrl.renderTemplate(w, r, "home.html", map[string]interface{}{
+		"BaseURL":           ctrl.AppBaseURL.String(),
+		"Term":              c.Term,
+		"Archived":          !c.Current,
+		"ArchivedCampaigns": ctrl.ArchivedCampaigns(),
+		"Municipalities":    c.Municipalities,
+		"Areas":             c.Areas,
+		"Scoreboard":        c.RecallLegislators.ToScoreboard(),
+		"Locatable":         len(c.WardBoundaries) > 0,
+	})
 }
 
-func (ctrl *Controller) Home(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/" {
-		ctrl.renderTemplate(w, "home.html", map[string]interface{}{
-			"BaseURL":        ctrl.AppBaseURL.String(),
-			"Municipalities": ctrl.Municipalities,
-			"Areas":          ctrl.Areas,
-		})
-	} else {
-		ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusNotFound, "您請求的頁面不存在", ctrl.AppBaseURL, ctrl.AppBaseURL))
+// campaignFromRequest resolves the optional "term" query parameter of the
+// APIs, defaulting to the current campaign.
+func (ctrl *Controller) campaignFromRequest(r *http.Request) (*Campaign, error) {
+	t := r.FormValue("term")
+	if t == "" {
+		return ctrl.Campaign, nil
+	}
+
+	term, err := strconv.ParseUint(t, 10, 64)
+	if err != nil {
+		return nil, err
 	}
+
+	c := ctrl.GetCampaign(term)
+	if c == nil {
+		return nil, fmt.Errorf("term %d not found", term)
+	}
+
+	return c, nil
 }
 
 func (ctrl *Controller) AuthorizationLetter(w http.ResponseWriter, r *http.Request) {
-	ctrl.renderTemplate(w, "authorization-letter.html", map[string]interface{}{
+	ctrl.renderTemplate(w, r, "authorization-letter.html", map[string]interface{}{
 		"BaseURL": ctrl.AppBaseURL.String(),
 	})
 }
@@ -64,6 +90,12 @@ func (ctrl *Controller) SearchRecallConstituency(w http.ResponseWriter, r *http.
 	r.ParseForm()
 	var qp RequestQuerySearchRecallConstituency
 
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+		return
+	}
+
 	m := r.FormValue("municipality")
 	mid, err := strconv.ParseUint(m, 10, 64)
 	if err != nil {
@@ -90,11 +122,23 @@ func (ctrl *Controller) SearchRecallConstituency(w http.ResponseWriter, r *http.
 		qp.WardId = &val
 	}
 
-	exists, divisions, legislators := ctrl.HasRecallLegislators(qp.MunicipalityId, qp.DistrictId, qp.WardId)
-	if !exists {
-		writeJSON(w, http.StatusNotFound, RespSearchRecallConstituency{
-			Message: http.StatusText(http.StatusNotFound),
-		})
+	divisions, legislators, err := c.FindRecallLegislators(qp.MunicipalityId, qp.DistrictId, qp.WardId)
+	if err != nil {
+		var notFound *DivisionNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			writeJSON(w, http.StatusNotFound, RespSearchRecallConstituency{
+				Message: notFound.Level + " not found",
+			})
+		case errors.Is(err, ErrDistrictRequired):
+			writeJSON(w, http.StatusBadRequest, RespSearchRecallConstituency{
+				Message: "district required",
+			})
+		default:
+			writeJSON(w, http.StatusNotFound, RespSearchRecallConstituency{
+				Message: http.StatusText(http.StatusNotFound),
+			})
+		}
 		return
 	}
 
@@ -107,8 +151,17 @@ func (ctrl *Controller) SearchRecallConstituency(w http.ResponseWriter, r *http.
 	})
 }
 
-func (ctrl *Controller) Participate(w http.ResponseWriter, r *http.Request, name string) {
-	l := ctrl.GetRecallLegislator(name)
+func (ctrl *Controller) Participate(w http.ResponseWriter, r *http.Request, c *Campaign, code string) {
+	l := c.GetRecallLegislatorByCode(code)
+	if l != nil && l.HasResult() {
+		ctrl.renderTemplate(w, r, "results.html", map[string]interface{}{
+			"BaseURL":    ctrl.AppBaseURL.String(),
+			"Legislator": l,
+			"Result":     l.Result,
+		})
+		return
+	}
+
 	if l == nil || l.RecallStatus != RecallStatusOngoing {
 		http.Redirect(w, r, ctrl.AppBaseURL.String(), http.StatusMovedPermanently)
 		return
@@ -121,7 +174,7 @@ func (ctrl *Controller) Participate(w http.ResponseWriter, r *http.Request, name
 
 	switch l.RecallStage {
 	case 1, 2:
-		ctrl.renderTemplate(w, "fill-form.html", map[string]interface{}{
+		ctrl.renderTemplate(w, r, "fill-form.html", map[string]interface{}{
 			"BaseURL":          ctrl.AppBaseURL.String(),
 			"PreviewURL":       l.ParticipateURL.JoinPath("preview").String(),
 			"Address":          address,
@@ -129,101 +182,107 @@ func (ctrl *Controller) Participate(w http.ResponseWriter, r *http.Request, name
 			"Legislator":       l,
 		})
 	case 3, 4:
-		ctrl.renderTemplate(w, "vote-reminder.html", map[string]interface{}{
-			"BaseURL":    ctrl.AppBaseURL.String(),
-			"Legislator": l,
+		var districts Divisions
+		if m := c.Municipality(l.MunicipalityId); m != nil {
+			districts = m.Divisions
+		}
+
+		ctrl.renderTemplate(w, r, "vote-reminder.html", map[string]interface{}{
+			"BaseURL":            ctrl.AppBaseURL.String(),
+			"ReminderURL":        l.ParticipateURL.JoinPath("vote-reminder.ics").String(),
+			"PollingStationsURL": ctrl.AppBaseURL.JoinPath("apis", "polling-stations").String(),
+			"Term":               c.Term,
+			"Districts":          districts,
+			"HasPollingStations": len(c.PollingStations) > 0,
+			"Legislator":         l,
 		})
 	default:
 		http.Redirect(w, r, ctrl.AppBaseURL.String(), http.StatusMovedPermanently)
 	}
 }
 
-func (ctrl *Controller) PreviewLocalForm(w http.ResponseWriter, r *http.Request, name string) {
-	l := ctrl.GetRecallLegislator(name)
+func (ctrl *Controller) PreviewLocalForm(w http.ResponseWriter, r *http.Request, c *Campaign, code string) {
+	l := c.GetRecallLegislatorByCode(code)
 	if l == nil || l.RecallStatus != RecallStatusOngoing || !l.IsPetitioning() {
-		ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusConflict, "候選人不處於連署階段", ctrl.AppBaseURL, ctrl.AppBaseURL))
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusConflict, MsgNotPetitioning, ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return
 	}
 
 	qp, err := getRequestForm(r)
 	if err != nil {
-		ctrl.renderTemplate(w, "4xx.html", ViewHttp4xxError{
-			HttpStatusCode: http.StatusBadRequest,
-			ErrorMessage:   "輸入有誤",
-			ReturnURL:      ctrl.AppBaseURL.String(),
-		})
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusBadRequest, MsgInvalidInput, ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return
 	}
 
-	up := RequestUriStageLegislator{Name: name, Stage: l.RecallStage}
+	up := RequestUriStageLegislator{Name: l.PoliticianName, Stage: l.RecallStage}
 	data, err := qp.ToPreviewData(ctrl.Config, &up, l)
 	if err != nil {
-		ctrl.renderTemplate(w, "4xx.html", ViewHttp4xxError{
-			HttpStatusCode: http.StatusBadRequest,
-			ErrorMessage:   err.Error(),
-			ReturnURL:      ctrl.AppBaseURL.String(),
-		})
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusBadRequest, err.Error(), ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return
 	}
 
-	tmpfile := l.GetTmplFilename()
-	ctrl.renderTemplate(w, tmpfile, data)
+	ctrl.renderPetitionForm(w, r, data)
 }
 
 func getRequestForm(r *http.Request) (*RequestForm, error) {
 	r.ParseForm()
 
-	name := strings.TrimSpace(r.FormValue("name"))
+	name := normalizeInput(r.FormValue("name"))
 	if name == "" {
 		return nil, fmt.Errorf("invalid name")
 	}
 
-	idNumber := strings.TrimSpace(r.FormValue("id-number"))
+	idNumber := normalizeIdNumber(r.FormValue("id-number"))
 	if idNumber == "" {
 		return nil, fmt.Errorf("invalid id-number")
 	}
 
-	birthYear := strings.TrimSpace(r.FormValue("birth-year"))
+	birthYear := normalizeInput(r.FormValue("birth-year"))
 	if birthYear == "" {
 		return nil, fmt.Errorf("invalid date")
 	}
 
-	birthMonth := strings.TrimSpace(r.FormValue("birth-month"))
+	birthMonth := normalizeInput(r.FormValue("birth-month"))
 	if birthMonth == "" {
 		return nil, fmt.Errorf("invalid date")
 	}
 
-	birthDay := strings.TrimSpace(r.FormValue("birth-day"))
+	birthDay := normalizeInput(r.FormValue("birth-day"))
 	if birthDay == "" {
 		return nil, fmt.Errorf("invalid date")
 	}
 
-	address := strings.TrimSpace(r.FormValue("address"))
+	birthDate, err := ParseROCDate(birthYear, birthMonth, birthDay, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	address := normalizeInput(r.FormValue("address"))
 	if address == "" {
 		return nil, fmt.Errorf("empty address")
 	}
 
-	mobileNumber := strings.TrimSpace(r.FormValue("mobile-number"))
+	mobileNumber := normalizeMobileNumber(r.FormValue("mobile-number"))
 
 	return &RequestForm{
 		Name:         name,
 		IdNumber:     idNumber,
-		BirthYear:    birthYear,
-		BirthMonth:   birthMonth,
-		BirthDay:     birthDay,
+		BirthYear:    strconv.Itoa(birthDate.Year),
+		BirthMonth:   strconv.Itoa(birthDate.Month),
+		BirthDay:     strconv.Itoa(birthDate.Day),
 		Address:      sanitizeAddress(address),
 		MobileNumber: mobileNumber,
 	}, nil
 }
 
-func (ctrl *Controller) ThankYou(w http.ResponseWriter, r *http.Request, name string) {
-	l := ctrl.GetRecallLegislator(name)
+func (ctrl *Controller) ThankYou(w http.ResponseWriter, r *http.Request, c *Campaign, code string) {
+	l := c.GetRecallLegislatorByCode(code)
 	if l == nil || l.RecallStatus != RecallStatusOngoing {
 		http.Redirect(w, r, ctrl.AppBaseURL.String(), http.StatusMovedPermanently)
 		return
 	}
 
-	ctrl.renderTemplate(w, "thank-you.html", map[string]interface{}{
+	ctrl.renderTemplate(w, r, "thank-you.html", map[string]interface{}{
 		"BaseURL":        ctrl.AppBaseURL.String(),
 		"ParticipateURL": l.ParticipateURL,
 		"CalendarURL":    l.CalendarURL,
@@ -231,10 +290,301 @@ func (ctrl *Controller) ThankYou(w http.ResponseWriter, r *http.Request, name st
 	})
 }
 
+func (ctrl *Controller) VoteReminder(w http.ResponseWriter, r *http.Request, c *Campaign, code string) {
+	l := c.GetRecallLegislatorByCode(code)
+	if l == nil || l.RecallStatus != RecallStatusOngoing || !l.IsVoting() || l.VotingDate == nil || *l.VotingDate == "" {
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgVotingDateNotAnnounced, ctrl.AppBaseURL, ctrl.AppBaseURL))
+		return
+	}
+
+	start, end, err := VotingHours(*l.VotingDate, Location)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	eventURL := l.ParticipateURL.String()
+	if l.VotingEventURL != nil && *l.VotingEventURL != "" {
+		eventURL = *l.VotingEventURL
+	}
+
+	locale := ctrl.Locale(w, r)
+	event := ICSEvent{
+		UID:     fmt.Sprintf("vote-%d-%s@%s", l.ConstituencyId, *l.VotingDate, ctrl.AppBaseURL.Hostname()),
+		Summary: ctrl.Catalogs.Message(locale, "ics.summary", "politician", l.PoliticianName),
+		Description: ctrl.Catalogs.Message(locale, "ics.description",
+			"constituency", l.ConstituencyName,
+			"politician", l.PoliticianName,
+			"start", fmt.Sprintf("%02d:00", VotingStartHour),
+			"end", fmt.Sprintf("%02d:00", VotingEndHour),
+		) + "\n" + eventURL,
+		URL:    eventURL,
+		Start:  start,
+		End:    end,
+		Alarms: []time.Duration{24 * time.Hour, 2 * time.Hour},
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="vote-reminder-%d.ics"`, l.ConstituencyId))
+	w.Write(event.ICS(time.Now()))
+}
+
+func (ctrl *Controller) SearchPollingStations(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+		return
+	}
+
+	ids := [3]uint64{}
+	for i, key := range []string{"municipality", "district", "ward"} {
+		val, err := strconv.ParseUint(r.FormValue(key), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": key + " error"})
+			return
+		}
+		ids[i] = val
+	}
+
+	ward := c.GetWard(ids[0], ids[1], ids[2])
+	if ward == nil {
+		writeJSON(w, http.StatusNotFound, RespSearchPollingStations{
+			Message: http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RespSearchPollingStations{
+		Message: http.StatusText(http.StatusOK),
+		Result: &ResultSearchPollingStations{
+			ConstituencyId:  ward.ConstituencyId,
+			PollingStations: c.PollingStations[ward.Id],
+		},
+	})
+}
+
+func (ctrl *Controller) SearchRecallResults(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+		return
+	}
+
+	legislators := RecallLegislators{}
+	if cs := r.FormValue("constituency"); cs != "" {
+		cid, err := strconv.ParseUint(cs, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "constituency error"})
+			return
+		}
+
+		for _, l := range c.RecallLegislatorMap[cid] {
+			if l.HasResult() {
+				legislators = append(legislators, l)
+			}
+		}
+
+		if len(legislators) == 0 {
+			writeJSON(w, http.StatusNotFound, RespSearchRecallResults{
+				Message: http.StatusText(http.StatusNotFound),
+			})
+			return
+		}
+	} else {
+		for _, l := range c.RecallLegislators {
+			if l.HasResult() {
+				legislators = append(legislators, l)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, RespSearchRecallResults{
+		Message: http.StatusText(http.StatusOK),
+		Result: &ResultSearchRecallResults{
+			Scoreboard:  c.RecallLegislators.ToScoreboard(),
+			Legislators: legislators,
+		},
+	})
+}
+
+func (ctrl *Controller) ConstituencyMap(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+		return
+	}
+
+	code := strings.Trim(strings.TrimPrefix(r.URL.Path, "/apis/maps/constituencies"), "/")
+	if code != "" {
+		var feature *GeoJSONFeature
+		if l := c.GetRecallLegislatorByCode(code); l != nil {
+			feature = c.ConstituencyFeature(l.ConstituencyId)
+		}
+		if feature == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": http.StatusText(http.StatusNotFound)})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/geo+json")
+		json.NewEncoder(w).Encode(feature)
+		return
+	}
+
+	fc := &GeoJSONFeatureCollection{Type: "FeatureCollection", Features: c.ConstituencyFeatures()}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	if ctrl.AppEnv == AppEnvProduction {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	json.NewEncoder(w).Encode(fc)
+}
+
+func (ctrl *Controller) LocateWard(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+		return
+	}
+
+	lat, err := strconv.ParseFloat(r.FormValue("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "lat error"})
+		return
+	}
+
+	lng, err := strconv.ParseFloat(r.FormValue("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "lng error"})
+		return
+	}
+
+	loc, exact := c.LocateWard(Position{lng, lat})
+	if loc == nil {
+		writeJSON(w, http.StatusNotFound, RespLocateWard{
+			Message: http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RespLocateWard{
+		Message: http.StatusText(http.StatusOK),
+		Result: &ResultLocateWard{
+			Municipality: loc.Municipality.Division,
+			District:     loc.District.Division,
+			Ward:         loc.Ward.Division,
+			Exact:        exact,
+			Legislators:  c.RecallLegislatorMap[loc.Ward.ConstituencyId],
+		},
+	})
+}
+
+func (ctrl *Controller) EligibilityPage(w http.ResponseWriter, r *http.Request) {
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
+		return
+	}
+
+	ctrl.renderTemplate(w, r, "eligibility.html", map[string]interface{}{
+		"BaseURL":         ctrl.AppBaseURL.String(),
+		"Term":            c.Term,
+		"Municipalities":  c.Municipalities,
+		"MinAge":          EligibilityMinAge,
+		"ResidenceMonths": EligibilityResidenceMonths,
+	})
+}
+
+// CheckEligibility answers whether someone may sign or vote in the recalls
+// of their ward. It only accepts POST so that birth dates stay out of URLs,
+// and nothing about the request is stored.
+func (ctrl *Controller) CheckEligibility(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, RespCheckEligibility{
+			Message: http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	r.ParseForm()
+
+	c, err := ctrl.campaignFromRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "term error"})
+		return
+	}
+
+	ids := [3]uint64{}
+	for i, key := range []string{"municipality", "district", "ward"} {
+		ids[i], err = strconv.ParseUint(r.FormValue(key), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": key + " error"})
+			return
+		}
+	}
+
+	now := time.Now().In(Location)
+
+	birthDate, err := ParseROCDate(
+		normalizeInput(r.FormValue("birth-year")),
+		normalizeInput(r.FormValue("birth-month")),
+		normalizeInput(r.FormValue("birth-day")),
+		now,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "birth date error"})
+		return
+	}
+
+	months, err := strconv.ParseUint(normalizeInput(r.FormValue("residence-months")), 10, 64)
+	if err != nil || months > 1200 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "residence-months error"})
+		return
+	}
+
+	_, legislators, err := c.FindRecallLegislators(ids[0], &ids[1], &ids[2])
+	if err != nil && !errors.Is(err, ErrNoRecallLegislators) {
+		writeJSON(w, http.StatusNotFound, RespCheckEligibility{
+			Message: http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	in := EligibilityInput{
+		BirthDate:       birthDate,
+		RegisteredSince: now.AddDate(0, -int(months), 0),
+	}
+
+	locale := ctrl.Locale(w, r)
+	result := &ResultCheckEligibility{Legislators: []*Eligibility{}}
+	for _, l := range legislators {
+		e := l.CheckEligibility(in, now)
+		for _, check := range e.Checks {
+			check.Message = ctrl.Catalogs.Message(locale, check.MessageKey, check.Args...)
+		}
+		result.Legislators = append(result.Legislators, e)
+	}
+
+	writeJSON(w, http.StatusOK, RespCheckEligibility{
+		Message: http.StatusText(http.StatusOK),
+		Result:  result,
+	})
+}
+
 func (ctrl *Controller) MParticipate(w http.ResponseWriter, r *http.Request) {
-	ctrl.renderTemplate(w, "mayor-fill-form.html", map[string]interface{}{
+	ctrl.renderTemplate(w, r, "mayor-fill-form.html", map[string]interface{}{
 		"BaseURL":          ctrl.AppBaseURL.String(),
 		"PreviewURL":       ctrl.AppBaseURL.JoinPath("mayor", "preview").String(),
+		"Constituency":     MayorCity,
+		"Politician":       MayorName,
 		"Address":          MayorCity,
 		"TurnstileSiteKey": ctrl.TurnstileSiteKey,
 	})
@@ -243,28 +593,20 @@ func (ctrl *Controller) MParticipate(w http.ResponseWriter, r *http.Request) {
 func (ctrl *Controller) MPreviewLocalForm(w http.ResponseWriter, r *http.Request) {
 	qp, err := getRequestForm(r)
 	if err != nil {
-		ctrl.renderTemplate(w, "4xx.html", ViewHttp4xxError{
-			HttpStatusCode: http.StatusBadRequest,
-			ErrorMessage:   "輸入有誤",
-			ReturnURL:      ctrl.AppBaseURL.String(),
-		})
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusBadRequest, MsgInvalidInput, ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return
 	}
 
 	data, err := qp.ToMayorPreviewData(ctrl.Config)
 	if err != nil {
-		ctrl.renderTemplate(w, "4xx.html", ViewHttp4xxError{
-			HttpStatusCode: http.StatusBadRequest,
-			ErrorMessage:   err.Error(),
-			ReturnURL:      ctrl.AppBaseURL.String(),
-		})
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusBadRequest, err.Error(), ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return
 	}
-	ctrl.renderTemplate(w, "stage-2-"+MayorName+".html", data)
+	ctrl.renderPetitionForm(w, r, data)
 }
 
 func (ctrl *Controller) MThankYou(w http.ResponseWriter, r *http.Request) {
-	ctrl.renderTemplate(w, "mayor-thank-you.html", map[string]interface{}{
+	ctrl.renderTemplate(w, r, "mayor-thank-you.html", map[string]interface{}{
 		"BaseURL":        ctrl.AppBaseURL.String(),
 		"ParticipateURL": ctrl.AppBaseURL.JoinPath("mayor"),
 		"CsoURL":         "https://www.facebook.com/hc.thebigrecall",
@@ -275,12 +617,12 @@ func (ctrl *Controller) VerifyTurnstile(w http.ResponseWriter, r *http.Request)
 	r.ParseForm()
 	token := r.FormValue("cf-turnstile-response")
 	if token == "" {
-		ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusBadRequest, "您的請求有誤，請回到首頁重新輸入。", ctrl.AppBaseURL, ctrl.AppBaseURL))
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusBadRequest, MsgBadRequest, ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return false
 	}
 	success, err := ctrl.VerifyTurnstileToken(token)
 	if err != nil || !success {
-		ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusForbidden, "驗證失敗，請回到首頁重新輸入", ctrl.AppBaseURL, ctrl.AppBaseURL))
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusForbidden, MsgVerificationFailed, ctrl.AppBaseURL, ctrl.AppBaseURL))
 		return false
 	}
 	return true
@@ -297,18 +639,72 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func (ctrl *Controller) renderTemplate(w http.ResponseWriter, name string, data interface{}) {
+// renderBufferPool holds the buffers pages are rendered into, so a failed
+// render can still send a clean error instead of half a page.
+var renderBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Buffers that grew past this are dropped rather than pooled, so one large
+// page does not pin its memory forever.
+const maxPooledRenderBuffer = 1 << 20
+
+// renderPetitionForm renders the petition form of data's politician and
+// stage, or 404 when it has no layout.
+func (ctrl *Controller) renderPetitionForm(w http.ResponseWriter, r *http.Request, data *PreviewData) {
+	data.Layout = ctrl.FormLayout(FormLayoutName(data.RecallStage, data.PoliticianName))
+	if data.Layout == nil {
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
+		return
+	}
+
+	ctrl.renderTemplate(w, r, PetitionFormTemplate, data)
+}
+
+func (ctrl *Controller) renderTemplate(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	ctrl.renderTemplateStatus(w, r, http.StatusOK, name, data)
+}
+
+// renderError renders the error page with the status code it describes.
+func (ctrl *Controller) renderError(w http.ResponseWriter, r *http.Request, e *ViewHttp4xxError) {
+	ctrl.renderTemplateStatus(w, r, e.HttpStatusCode, "4xx.html", e)
+}
+
+// renderTemplateStatus renders name into a buffer and only writes the response
+// once rendering succeeded; otherwise it replies 500.
+func (ctrl *Controller) renderTemplateStatus(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) {
+	locale := ctrl.Locale(w, r)
+
+	buf := renderBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledRenderBuffer {
+			renderBufferPool.Put(buf)
+		}
+	}()
+
 	if ctrl.AppEnv == AppEnvProduction {
-		if err := ctrl.Templates.ExecuteTemplate(w, name, data); err != nil {
+		if err := ctrl.Templates.ExecuteTemplate(buf, locale, name, data); err != nil {
+			log.Printf("render %s: %v", name, err)
 			http.Error(w, "Template rendering error", http.StatusInternalServerError)
+			return
 		}
 	} else {
-		if t, err := template.ParseFiles("templates/tmpl.html", "templates/"+name); err != nil {
+		if t, err := template.New(name).Funcs(ctrl.Catalogs.FuncMap(locale)).ParseFiles(filepath.Join(ctrl.TemplatesDir, "tmpl.html"), filepath.Join(ctrl.TemplatesDir, name)); err != nil {
 			http.Error(w, fmt.Errorf("Template parsing error: %v", err).Error(), http.StatusInternalServerError)
-		} else if err := t.ExecuteTemplate(w, name, data); err != nil {
+			return
+		} else if err := t.ExecuteTemplate(buf, name, data); err != nil {
 			http.Error(w, fmt.Errorf("Template rendering error: %v", err).Error(), http.StatusInternalServerError)
+			return
 		}
 	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", locale)
+	w.WriteHeader(status)
+	buf.WriteTo(w)
 }
 
 type SitemapURL struct {
@@ -329,7 +725,7 @@ func (ctrl *Controller) Ping(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ctrl *Controller) RobotsTxt(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("templates/robots.txt")
+	tmpl, err := template.ParseFiles(filepath.Join(ctrl.TemplatesDir, "robots.txt"))
 	if err != nil {
 		http.Error(w, "Template Error", http.StatusInternalServerError)
 		return
@@ -346,6 +742,7 @@ func (ctrl *Controller) Sitemap(w http.ResponseWriter, r *http.Request) {
 	urls := []*SitemapURL{
 		{ctrl.AppBaseURL.String(), date, "daily", "1.0"},
 		{ctrl.AppBaseURL.JoinPath("authorization-letter").String(), "2025-02-26", "yearly", "1.0"},
+		{ctrl.AppBaseURL.JoinPath("eligibility").String(), date, "monthly", "0.8"},
 		{ctrl.AppBaseURL.JoinPath("mayor").String(), "2025-03-12", "weekly", "0.9"},
 		{ctrl.AppBaseURL.JoinPath("mayor", "thank-you").String(), "2025-03-12", "weekly", "0.9"},
 	}
@@ -356,6 +753,21 @@ func (ctrl *Controller) Sitemap(w http.ResponseWriter, r *http.Request) {
 				&SitemapURL{l.ParticipateURL.String(), date, "weekly", "0.9"},
 				&SitemapURL{l.ParticipateURL.JoinPath("thank-you").String(), date, "weekly", "0.8"},
 			)
+		} else if l.HasResult() {
+			lastMod := date
+			if l.Result.AnnouncedAt != "" {
+				lastMod = l.Result.AnnouncedAt
+			}
+			urls = append(urls, &SitemapURL{l.ParticipateURL.String(), lastMod, "yearly", "0.7"})
+		}
+	}
+
+	for _, c := range ctrl.ArchivedCampaigns() {
+		urls = append(urls, &SitemapURL{c.BaseURL.String(), date, "yearly", "0.5"})
+		for _, l := range c.RecallLegislators {
+			if l.HasResult() {
+				urls = append(urls, &SitemapURL{l.ParticipateURL.String(), date, "yearly", "0.5"})
+			}
 		}
 	}
 
@@ -390,37 +802,122 @@ func (ctrl *Controller) GetAsset(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filePath)
 }
 
+// LegislatorRouter permanently redirects the legacy /legislators/{name} URLs
+// to the ASCII /c/{constituencyCode} ones.
 func (ctrl *Controller) LegislatorRouter(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/legislators/")
+	ctrl.redirectLegislator(w, r, ctrl.Campaign, strings.TrimPrefix(r.URL.Path, "/legislators/"))
+}
+
+func (ctrl *Controller) ConstituencyRouter(w http.ResponseWriter, r *http.Request) {
+	ctrl.constituencyRouter(w, r, ctrl.Campaign, strings.TrimPrefix(r.URL.Path, "/c/"))
+}
+
+// TermRouter serves archived campaigns under /terms/{term}, mirroring the
+// routes of the current campaign.
+func (ctrl *Controller) TermRouter(w http.ResponseWriter, r *http.Request) {
+	term, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/terms/"), "/")
+
+	t, err := strconv.ParseUint(term, 10, 64)
+	c := ctrl.GetCampaign(t)
+	if err != nil || c == nil {
+		ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
+		return
+	}
+
+	if c.Current {
+		target := ctrl.AppBaseURL
+		if rest != "" {
+			target = target.JoinPath(rest)
+		}
+
+		redirectPermanent(w, r, target)
+		return
+	}
+
+	if rest == "" && r.Method == http.MethodGet {
+		ctrl.CampaignHome(w, r, c)
+		return
+	}
+
+	if code, found := strings.CutPrefix(rest, "c/"); found {
+		ctrl.constituencyRouter(w, r, c, code)
+		return
+	}
+
+	if name, found := strings.CutPrefix(rest, "legislators/"); found {
+		ctrl.redirectLegislator(w, r, c, name)
+		return
+	}
+
+	ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, c.BaseURL))
+}
+
+func (ctrl *Controller) redirectLegislator(w http.ResponseWriter, r *http.Request, c *Campaign, path string) {
+	name, rest, _ := strings.Cut(path, "/")
+
+	l := c.GetRecallLegislator(name)
+	if l == nil {
+		http.Redirect(w, r, c.BaseURL.String(), http.StatusMovedPermanently)
+		return
+	}
+
+	target := l.ParticipateURL
+	if rest != "" {
+		target = target.JoinPath(rest)
+	}
+
+	redirectPermanent(w, r, target)
+}
+
+// redirectPermanent redirects to target, keeping the query of the request.
+func redirectPermanent(w http.ResponseWriter, r *http.Request, target *url.URL) {
+	u := *target
+	u.RawQuery = r.URL.RawQuery
+
+	// 301 turns a POST into a GET; keep the method for form submissions.
+	status := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+
+	http.Redirect(w, r, u.String(), status)
+}
+
+func (ctrl *Controller) constituencyRouter(w http.ResponseWriter, r *http.Request, c *Campaign, path string) {
 	parts := strings.Split(path, "/")
 
 	if len(parts) == 1 && r.Method == http.MethodGet {
-		ctrl.Participate(w, r, parts[0])
+		ctrl.Participate(w, r, c, parts[0])
 		return
 	}
 
 	if len(parts) == 2 {
-		name := parts[0]
+		code := parts[0]
 		switch parts[1] {
 		case "preview":
 			if r.Method == http.MethodPost {
 				if !ctrl.VerifyTurnstile(w, r) {
-					ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusBadRequest, "不合法的請求", ctrl.AppBaseURL, ctrl.AppBaseURL))
+					ctrl.renderError(w, r, GetViewHttpError(http.StatusBadRequest, MsgInvalidRequest, ctrl.AppBaseURL, ctrl.AppBaseURL))
 					return
 				} else {
-					ctrl.PreviewLocalForm(w, r, name)
+					ctrl.PreviewLocalForm(w, r, c, code)
 					return
 				}
 			}
 		case "thank-you":
 			if r.Method == http.MethodGet {
-				ctrl.ThankYou(w, r, name)
+				ctrl.ThankYou(w, r, c, code)
+				return
+			}
+		case "vote-reminder.ics":
+			if r.Method == http.MethodGet {
+				ctrl.VoteReminder(w, r, c, code)
 				return
 			}
 		}
 	}
 
-	ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusNotFound, "您請求的頁面不存在", ctrl.AppBaseURL, ctrl.AppBaseURL))
+	ctrl.renderError(w, r, GetViewHttpError(http.StatusNotFound, MsgPageNotFound, ctrl.AppBaseURL, ctrl.AppBaseURL))
 }
 
 type RequestQuerySearchRecallConstituency struct {
@@ -439,6 +936,48 @@ type ResultSearchRecallConstituency struct {
 	Legislators RecallLegislators `json:"legislators,omitempty"`
 }
 
+type RespSearchPollingStations struct {
+	Message string                       `json:"message"`
+	Result  *ResultSearchPollingStations `json:"result,omitempty"`
+}
+
+type ResultSearchPollingStations struct {
+	ConstituencyId  uint64            `json:"constituencyId"`
+	PollingStations []*PollingStation `json:"pollingStations"`
+}
+
+type RespSearchRecallResults struct {
+	Message string                     `json:"message"`
+	Result  *ResultSearchRecallResults `json:"result,omitempty"`
+}
+
+type ResultSearchRecallResults struct {
+	Scoreboard  *Scoreboard       `json:"scoreboard"`
+	Legislators RecallLegislators `json:"legislators"`
+}
+
+type RespLocateWard struct {
+	Message string            `json:"message"`
+	Result  *ResultLocateWard `json:"result,omitempty"`
+}
+
+type ResultLocateWard struct {
+	Municipality *Division         `json:"municipality"`
+	District     *Division         `json:"district"`
+	Ward         *Division         `json:"ward"`
+	Exact        bool              `json:"exact"`
+	Legislators  RecallLegislators `json:"legislators"`
+}
+
+type RespCheckEligibility struct {
+	Message string                  `json:"message"`
+	Result  *ResultCheckEligibility `json:"result,omitempty"`
+}
+
+type ResultCheckEligibility struct {
+	Legislators []*Eligibility `json:"legislators"`
+}
+
 type RequestForm struct {
 	Name         string
 	IdNumber     string
@@ -470,53 +1009,24 @@ func (ctrl *Controller) PreviewOriginalLocalForm(w http.ResponseWriter, r *http.
 
 	var data *PreviewData
 	if name != MayorName {
-		l := ctrl.GetRecallLegislator(name)
+		l, canonical := ctrl.Lookup(name)
 		if l == nil {
 			http.Redirect(w, r, ctrl.AppBaseURL.String(), http.StatusMovedPermanently)
 			return
 		}
 
-		data = &PreviewData{
-			BaseURL:          ctrl.AppBaseURL.String(),
-			ParticipateURL:   l.ParticipateURL,
-			RedirectURL:      l.ParticipateURL.JoinPath("thank-you").String(),
-			PoliticianName:   name,
-			ConstituencyName: l.ConstituencyName,
-			RecallStage:      stage,
-			Name:             "邱吉爾",
-			IdNumber:         IdNumber{"A", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
-			BirthYear:        "888",
-			BirthMonth:       "11",
-			BirthDate:        "30",
-			MobileNumber:     "0987654321",
-			Address:          "某某市某某區某某里某某路三段 123 號七樓一段超長的地址一段超長的地址一段超長的地址一段超長的地址一段超長的地址",
-		}
-	} else {
-		participateURL := ctrl.AppBaseURL.JoinPath("mayor")
-		data = &PreviewData{
-			BaseURL:          ctrl.AppBaseURL.String(),
-			ParticipateURL:   participateURL,
-			RedirectURL:      participateURL.JoinPath("thank-you").String(),
-			PoliticianName:   name,
-			ConstituencyName: MayorCity,
-			RecallStage:      stage,
-			Name:             "邱吉爾",
-			IdNumber:         IdNumber{"A", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
-			BirthYear:        "888",
-			BirthMonth:       "11",
-			BirthDate:        "30",
-			MobileNumber:     "0987654321",
-			Address:          "某某市某某區某某里某某路三段 123 號七樓一段超長的地址一段超長的地址一段超長的地址一段超長的地址一段超長的地址",
+		if !canonical {
+			u := ctrl.AppBaseURL.JoinPath("preview", "stages", parts[0], l.PoliticianName)
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
 		}
-	}
 
-	if stage == 2 {
-		tmpl := fmt.Sprintf("stage-2-%s.html", name)
-		ctrl.renderTemplate(w, tmpl, data)
-		return
+		data = NewSamplePreviewData(ctrl.AppBaseURL, l.ParticipateURL, name, l.ConstituencyName, stage)
+	} else {
+		data = NewSamplePreviewData(ctrl.AppBaseURL, ctrl.AppBaseURL.JoinPath("mayor"), name, MayorCity, stage)
 	}
 
-	ctrl.renderTemplate(w, "4xx.html", GetViewHttpError(http.StatusNotFound, "您請求的頁面不存在", ctrl.AppBaseURL, ctrl.AppBaseURL))
+	ctrl.renderPetitionForm(w, r, data)
 }
 
 type PreviewData struct {
@@ -526,7 +1036,6 @@ type PreviewData struct {
 	PoliticianName   string
 	ConstituencyName string
 	RecallStage      uint64
-	ImagePrefix      string
 	Name             string
 	IdNumber         IdNumber
 	BirthYear        string
@@ -534,6 +1043,27 @@ type PreviewData struct {
 	BirthDate        string
 	MobileNumber     string
 	Address          string
+	Layout           *FormLayout
+}
+
+// NewSamplePreviewData fills a petition form with made-up data, long enough
+// to show how the layout handles overflowing addresses.
+func NewSamplePreviewData(baseURL, participateURL *url.URL, politicianName, constituencyName string, stage uint64) *PreviewData {
+	return &PreviewData{
+		BaseURL:          baseURL.String(),
+		ParticipateURL:   participateURL,
+		RedirectURL:      participateURL.JoinPath("thank-you").String(),
+		PoliticianName:   politicianName,
+		ConstituencyName: constituencyName,
+		RecallStage:      stage,
+		Name:             "邱吉爾",
+		IdNumber:         IdNumber{"A", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		BirthYear:        "888",
+		BirthMonth:       "11",
+		BirthDate:        "30",
+		MobileNumber:     "0987654321",
+		Address:          "某某市某某區某某里某某路三段 123 號七樓一段超長的地址一段超長的地址一段超長的地址一段超長的地址一段超長的地址",
+	}
 }
 
 type IdNumber struct {
@@ -550,19 +1080,17 @@ type IdNumber struct {
 }
 
 func (r RequestForm) ToPreviewData(cfg *Config, up *RequestUriStageLegislator, l *RecallLegislator) (*PreviewData, error) {
-	if !isValidIdNumber(r.IdNumber) {
-		return nil, fmt.Errorf("身份證輸入錯誤")
+	if err := ValidatePetitionIdNumber(r.IdNumber); err != nil {
+		return nil, errors.New(idNumberMessageKey(err))
 	}
 
 	if l.RecallStage == 1 {
 		if r.MobileNumber != "" && !isValidMobileNumber(r.MobileNumber) {
-			return nil, fmt.Errorf("手機號碼輸入錯誤")
+			return nil, errors.New(MsgInvalidMobileNumber)
 		}
 	}
 
-	stage := strconv.FormatUint(up.Stage, 10)
 	redirectURL := l.ParticipateURL.JoinPath("thank-you")
-	imagePrefix := fmt.Sprintf("stage-%s-%s", stage, up.Name)
 
 	data := &PreviewData{
 		BaseURL:          cfg.AppBaseURL.String(),
@@ -571,7 +1099,6 @@ func (r RequestForm) ToPreviewData(cfg *Config, up *RequestUriStageLegislator, l
 		PoliticianName:   up.Name,
 		ConstituencyName: l.ConstituencyName,
 		RecallStage:      up.Stage,
-		ImagePrefix:      imagePrefix,
 		Name:             r.Name,
 		BirthYear:        r.BirthYear,
 		BirthMonth:       r.BirthMonth,
@@ -609,12 +1136,11 @@ func (r RequestForm) ToPreviewData(cfg *Config, up *RequestUriStageLegislator, l
 }
 
 func (r RequestForm) ToMayorPreviewData(cfg *Config) (*PreviewData, error) {
-	if !isValidIdNumber(r.IdNumber) {
-		return nil, fmt.Errorf("身份證輸入錯誤")
+	if err := ValidatePetitionIdNumber(r.IdNumber); err != nil {
+		return nil, errors.New(idNumberMessageKey(err))
 	}
 
 	redirectURL := cfg.AppBaseURL.JoinPath("mayor", "thank-you")
-	imagePrefix := "stage-2-" + MayorName
 
 	data := &PreviewData{
 		BaseURL:          cfg.AppBaseURL.String(),
@@ -623,7 +1149,6 @@ func (r RequestForm) ToMayorPreviewData(cfg *Config) (*PreviewData, error) {
 		PoliticianName:   MayorName,
 		ConstituencyName: MayorCity,
 		RecallStage:      2,
-		ImagePrefix:      imagePrefix,
 		Name:             r.Name,
 		BirthYear:        r.BirthYear,
 		BirthMonth:       r.BirthMonth,
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+)
+
+// Recall petitioners and voters must be at least EligibilityMinAge and have
+// held household registration in the constituency for at least
+// EligibilityResidenceMonths, both counted at the reference date of the
+// stage they take part in.
+const (
+	EligibilityMinAge          = 18
+	EligibilityResidenceMonths = 4
+)
+
+const (
+	EligibilityRuleStatus    = "status"
+	EligibilityRuleAge       = "age"
+	EligibilityRuleResidence = "residence"
+)
+
+const (
+	EligibilityReferenceVoting   = "voting"
+	EligibilityReferencePetition = "petition"
+	EligibilityReferenceToday    = "today"
+)
+
+// EligibilityInput is what a would-be signer tells us. It is only used to
+// answer the request and never stored.
+type EligibilityInput struct {
+	BirthDate       ROCDate
+	RegisteredSince time.Time
+}
+
+type Eligibility struct {
+	ConstituencyCode string              `json:"constituencyCode"`
+	ConstituencyName string              `json:"constituencyName"`
+	PoliticianName   string              `json:"politicianName"`
+	ParticipateURL   string              `json:"participateURL"`
+	Reference        string              `json:"reference"`
+	ReferenceDate    string              `json:"referenceDate"`
+	Eligible         bool                `json:"eligible"`
+	Checks           []*EligibilityCheck `json:"checks"`
+}
+
+type EligibilityCheck struct {
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+
+	// MessageKey and Args are rendered into Message in the request locale.
+	MessageKey string        `json:"-"`
+	Args       []interface{} `json:"-"`
+}
+
+// EligibilityReference returns the date eligibility is counted at: the voting
+// date once voting is scheduled, otherwise the petition cutoff date, or today
+// when neither is known.
+func (r RecallLegislator) EligibilityReference(now time.Time) (string, time.Time) {
+	loc := now.Location()
+	if r.IsVoting() && r.VotingDate != nil {
+		if t, err := time.ParseInLocation("2006-01-02", *r.VotingDate, loc); err == nil {
+			return EligibilityReferenceVoting, t
+		}
+	}
+
+	if r.SafetyCutoffDate != nil {
+		if t, err := time.ParseInLocation("2006-01-02", *r.SafetyCutoffDate, loc); err == nil && t.After(now) {
+			return EligibilityReferencePetition, t
+		}
+	}
+
+	y, m, d := now.Date()
+	return EligibilityReferenceToday, time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// CheckEligibility evaluates the recall eligibility rules for one legislator.
+func (r *RecallLegislator) CheckEligibility(in EligibilityInput, now time.Time) *Eligibility {
+	reference, ref := r.EligibilityReference(now)
+
+	e := &Eligibility{
+		ConstituencyCode: r.ConstituencyCode,
+		ConstituencyName: r.ConstituencyName,
+		PoliticianName:   r.PoliticianName,
+		ParticipateURL:   r.ParticipateURLString,
+		Reference:        reference,
+		ReferenceDate:    ref.Format("2006-01-02"),
+		Eligible:         true,
+	}
+
+	if r.RecallStatus != RecallStatusOngoing {
+		e.Eligible = false
+		e.Checks = append(e.Checks, &EligibilityCheck{
+			Rule:       EligibilityRuleStatus,
+			MessageKey: "eligibility.status.closed",
+		})
+		return e
+	}
+
+	adult := in.BirthDate.Time(now.Location()).AddDate(EligibilityMinAge, 0, 0)
+	age := &EligibilityCheck{
+		Rule:   EligibilityRuleAge,
+		Passed: !adult.After(ref),
+		Args:   []interface{}{"date", ref, "adultDate", adult, "age", EligibilityMinAge},
+	}
+	age.MessageKey = "eligibility.age.passed"
+	if !age.Passed {
+		age.MessageKey = "eligibility.age.failed"
+	}
+
+	deadline := ref.AddDate(0, -EligibilityResidenceMonths, 0)
+	residence := &EligibilityCheck{
+		Rule:   EligibilityRuleResidence,
+		Passed: !in.RegisteredSince.After(deadline),
+		Args:   []interface{}{"date", ref, "deadline", deadline, "months", EligibilityResidenceMonths},
+	}
+	residence.MessageKey = "eligibility.residence.passed"
+	if !residence.Passed {
+		residence.MessageKey = "eligibility.residence.failed"
+	}
+
+	e.Checks = append(e.Checks, age, residence)
+	for _, c := range e.Checks {
+		e.Eligible = e.Eligible && c.Passed
+	}
+
+	return e
+}
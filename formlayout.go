@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// JSONConfigFormLayoutsDir, in the data directory, holds one layout per
+// petition form, named after FormLayoutName, e.g. stage-2-林沛祥.json. Adding
+// a form is a layout plus its background image in FormImagesDir.
+const (
+	JSONConfigFormLayoutsDir = "form-layouts"
+	FormImagesDir            = "assets/images"
+)
+
+const (
+	FormFieldName         = "name"
+	FormFieldIdNumber     = "idNumber"
+	FormFieldBirthDate    = "birthDate"
+	FormFieldAddress      = "address"
+	FormFieldMobileNumber = "mobileNumber"
+	FormFieldSignature    = "signature" // where to sign, left blank
+)
+
+// FormFields lists the fields a layout can place, in the order the
+// calibration tool offers them.
+var FormFields = []string{
+	FormFieldName,
+	FormFieldIdNumber,
+	FormFieldBirthDate,
+	FormFieldAddress,
+	FormFieldMobileNumber,
+	FormFieldSignature,
+}
+
+const (
+	DefaultFormOrientation = "landscape"
+	DefaultFormUnit        = "%"
+	DefaultBirthDateFormat = "{year} 年 {month} 月 {day} 日"
+)
+
+// FormLayoutName returns the name of the layout of a politician's petition
+// form at stage, which is also the name of its background image.
+func FormLayoutName(stage uint64, politicianName string) string {
+	return fmt.Sprintf("stage-%d-%s", stage, politicianName)
+}
+
+// FormLayout places the fields of PreviewData on the scanned petition form.
+// Positions are relative to the page in Unit: "%" (default), "mm" or "px".
+type FormLayout struct {
+	Image        string       `json:"image"`                  // file in FormImagesDir
+	ImageVersion string       `json:"imageVersion,omitempty"` // bumped to bust caches
+	ImageRotate  float64      `json:"imageRotate,omitempty"`  // degrees, to straighten scans
+	Orientation  string       `json:"orientation,omitempty"`  // landscape (default) or portrait
+	Unit         string       `json:"unit,omitempty"`
+	Fields       []*FormField `json:"fields"`
+}
+
+type FormField struct {
+	Field           string  `json:"field"`
+	Size            string  `json:"size,omitempty"` // font size class in preview.css, e.g. md
+	Left            float64 `json:"left"`
+	Top             float64 `json:"top"`
+	Width           float64 `json:"width,omitempty"`
+	Height          float64 `json:"height,omitempty"`
+	Align           string  `json:"align,omitempty"`         // left, center or right
+	LetterSpacing   float64 `json:"letterSpacing,omitempty"` // em
+	LineHeight      float64 `json:"lineHeight,omitempty"`    // px
+	Padding         float64 `json:"padding,omitempty"`       // px
+	WhiteBackground bool    `json:"whiteBackground,omitempty"`
+
+	// Format of birthDate with {year}, {month} and {day}; a newline breaks
+	// the line. Defaults to DefaultBirthDateFormat.
+	Format string `json:"format,omitempty"`
+
+	unit string
+}
+
+type FormLayouts map[string]*FormLayout // string: FormLayoutName
+
+// FormLayout returns the layout called name. The calibration tool replaces
+// layouts while the server runs.
+func (ctrl *Controller) FormLayout(name string) *FormLayout {
+	ctrl.formLayoutsMu.RLock()
+	defer ctrl.formLayoutsMu.RUnlock()
+
+	return ctrl.FormLayouts[name]
+}
+
+func (ctrl *Controller) SetFormLayout(name string, layout *FormLayout) {
+	ctrl.formLayoutsMu.Lock()
+	defer ctrl.formLayoutsMu.Unlock()
+
+	ctrl.FormLayouts[name] = layout
+}
+
+// config: form-layouts
+func ReadConfigFormLayouts(dir string) (FormLayouts, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := FormLayouts{}
+	for _, p := range paths {
+		name := strings.TrimSuffix(filepath.Base(p), ".json")
+
+		layout, err := ReadFormLayout(p)
+		if err != nil {
+			return nil, fmt.Errorf("form-layouts: %s: %w", name, err)
+		}
+
+		layouts[name] = layout
+	}
+
+	return layouts, nil
+}
+
+func ReadFormLayout(path string) (*FormLayout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	layout := &FormLayout{}
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(layout); err != nil {
+		return nil, err
+	}
+
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+
+	return layout, nil
+}
+
+// Validate checks the layout. Defaults are left empty so saved layouts only
+// spell out what differs from them.
+func (l *FormLayout) Validate() error {
+	if l.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+	if filepath.Base(l.Image) != l.Image {
+		return fmt.Errorf("image %q must be a file name", l.Image)
+	}
+	if _, err := os.Stat(filepath.Join(FormImagesDir, l.Image)); err != nil {
+		return fmt.Errorf("image: %w", err)
+	}
+
+	switch l.Orientation {
+	case DefaultFormOrientation:
+		l.Orientation = ""
+	case "", "portrait":
+	default:
+		return fmt.Errorf("unknown orientation %q", l.Orientation)
+	}
+
+	switch l.Unit {
+	case DefaultFormUnit:
+		l.Unit = ""
+	case "", "mm", "px":
+	default:
+		return fmt.Errorf("unknown unit %q", l.Unit)
+	}
+
+	for i, f := range l.Fields {
+		if !isFormField(f.Field) {
+			return fmt.Errorf("fields[%d]: unknown field %q", i, f.Field)
+		}
+
+		switch f.Align {
+		case "", "left", "center", "right":
+		default:
+			return fmt.Errorf("fields[%d]: unknown align %q", i, f.Align)
+		}
+
+		if strings.ContainsAny(f.Size, " \"'<>") {
+			return fmt.Errorf("fields[%d]: invalid size %q", i, f.Size)
+		}
+
+		if f.Format == DefaultBirthDateFormat {
+			f.Format = ""
+		}
+
+		f.unit = l.PositionUnit()
+	}
+
+	return nil
+}
+
+func isFormField(field string) bool {
+	for _, f := range FormFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PageOrientation returns landscape or portrait, the a4- class in preview.css.
+func (l *FormLayout) PageOrientation() string {
+	if l.Orientation == "" {
+		return DefaultFormOrientation
+	}
+
+	return l.Orientation
+}
+
+func (l *FormLayout) PositionUnit() string {
+	if l.Unit == "" {
+		return DefaultFormUnit
+	}
+
+	return l.Unit
+}
+
+func (l *FormLayout) ImageURL() string {
+	if l.ImageVersion == "" {
+		return l.Image
+	}
+
+	return l.Image + "?v=" + l.ImageVersion
+}
+
+func (l *FormLayout) ImageStyle() template.CSS {
+	if l.ImageRotate == 0 {
+		return ""
+	}
+
+	return template.CSS("transform:rotate(" + formatCSSNumber(l.ImageRotate) + "deg);")
+}
+
+// Class returns the classes of the field in preview.css.
+func (f *FormField) Class() string {
+	classes := []string{"inputField", "center"}
+	if f.Field == FormFieldSignature {
+		classes = []string{"whereToSign"}
+	}
+
+	if f.Size != "" {
+		classes = append(classes, f.Size)
+	}
+	if f.WhiteBackground {
+		classes = append(classes, "whiteBg")
+	}
+
+	return strings.Join(classes, " ")
+}
+
+// Style returns the inline position of the field.
+func (f *FormField) Style() template.CSS {
+	props := []string{}
+	if f.Align != "" {
+		props = append(props, "text-align:"+f.Align)
+	}
+
+	props = append(props,
+		"left:"+formatCSSNumber(f.Left)+f.unit,
+		"top:"+formatCSSNumber(f.Top)+f.unit,
+	)
+
+	if f.Width != 0 {
+		props = append(props, "width:"+formatCSSNumber(f.Width)+f.unit)
+	}
+	if f.Height != 0 {
+		props = append(props, "height:"+formatCSSNumber(f.Height)+f.unit)
+	}
+	if f.LetterSpacing != 0 {
+		props = append(props, "letter-spacing:"+formatCSSNumber(f.LetterSpacing)+"em")
+	}
+	if f.LineHeight != 0 {
+		props = append(props, "line-height:"+formatCSSNumber(f.LineHeight)+"px")
+	}
+	if f.Padding != 0 {
+		props = append(props, "padding:"+formatCSSNumber(f.Padding)+"px")
+	}
+
+	return template.CSS(strings.Join(props, "; ") + ";")
+}
+
+// Lines returns the text the field shows for data, one entry per line.
+func (f *FormField) Lines(data *PreviewData) []string {
+	switch f.Field {
+	case FormFieldName:
+		return []string{data.Name}
+	case FormFieldIdNumber:
+		id := data.IdNumber
+		return []string{id.D0 + id.D1 + id.D2 + id.D3 + id.D4 + id.D5 + id.D6 + id.D7 + id.D8 + id.D9}
+	case FormFieldBirthDate:
+		format := f.Format
+		if format == "" {
+			format = DefaultBirthDateFormat
+		}
+
+		date := strings.NewReplacer(
+			"{year}", data.BirthYear,
+			"{month}", data.BirthMonth,
+			"{day}", data.BirthDate,
+		).Replace(format)
+		return strings.Split(date, "\n")
+	case FormFieldAddress:
+		return []string{data.Address}
+	case FormFieldMobileNumber:
+		return []string{data.MobileNumber}
+	}
+
+	return nil
+}
+
+func formatCSSNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
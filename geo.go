@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// JSONConfigWardBoundaries is a TopoJSON topology with one geometry per ward
+// in its "wards" object, identified by the ward id. It is expected to be
+// simplified from the MOI village boundary dataset and converted to WGS84.
+// The home page offers to locate the ward only when a campaign has
+// boundaries.
+const JSONConfigWardBoundaries = "ward-boundaries.topo.json"
+
+// config: ward-boundaries
+func ReadConfigWardBoundaries(dir string) (WardBoundaries, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigWardBoundaries))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	topo := Topology{}
+
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&topo); err != nil {
+		return nil, err
+	}
+
+	return topo.WardBoundaries("wards")
+}
+
+type Topology struct {
+	Type      string                     `json:"type"`
+	Transform *TopologyTransform         `json:"transform"`
+	Arcs      [][][]float64              `json:"arcs"`
+	Objects   map[string]*TopologyObject `json:"objects"`
+}
+
+type TopologyTransform struct {
+	Scale     [2]float64 `json:"scale"`
+	Translate [2]float64 `json:"translate"`
+}
+
+type TopologyObject struct {
+	Type       string            `json:"type"`
+	Id         uint64            `json:"id"`
+	Arcs       json.RawMessage   `json:"arcs"`
+	Geometries []*TopologyObject `json:"geometries"`
+}
+
+// decodeArcs converts the (possibly quantized and delta-encoded) arcs to
+// absolute longitude/latitude positions.
+func (t Topology) decodeArcs() [][]Position {
+	arcs := make([][]Position, len(t.Arcs))
+	for i, arc := range t.Arcs {
+		x, y := 0.0, 0.0
+		positions := make([]Position, 0, len(arc))
+		for _, p := range arc {
+			if len(p) < 2 {
+				continue
+			}
+
+			if t.Transform == nil {
+				positions = append(positions, Position{p[0], p[1]})
+				continue
+			}
+
+			x += p[0]
+			y += p[1]
+			positions = append(positions, Position{
+				x*t.Transform.Scale[0] + t.Transform.Translate[0],
+				y*t.Transform.Scale[1] + t.Transform.Translate[1],
+			})
+		}
+		arcs[i] = positions
+	}
+
+	return arcs
+}
+
+func (t Topology) WardBoundaries(object string) (WardBoundaries, error) {
+	if t.Type != "Topology" {
+		return nil, fmt.Errorf("ward-boundaries: unexpected type %q", t.Type)
+	}
+
+	obj, exists := t.Objects[object]
+	if !exists {
+		return nil, fmt.Errorf("ward-boundaries: object %q not found", object)
+	}
+
+	arcs := t.decodeArcs()
+	ring := func(indexes []int) (Ring, error) {
+		r := Ring{}
+		for _, i := range indexes {
+			reversed := i < 0
+			if reversed {
+				i = ^i
+			}
+			if i >= len(arcs) {
+				return nil, fmt.Errorf("ward-boundaries: arc %d out of range", i)
+			}
+
+			arc := arcs[i]
+			if reversed {
+				arc = make([]Position, len(arcs[i]))
+				for j, p := range arcs[i] {
+					arc[len(arc)-1-j] = p
+				}
+			}
+
+			// consecutive arcs share their joining position
+			if len(r) > 0 && len(arc) > 0 {
+				arc = arc[1:]
+			}
+			r = append(r, arc...)
+		}
+		return r, nil
+	}
+
+	boundaries := WardBoundaries{}
+	for _, g := range obj.Geometries {
+		var polygons [][][]int
+		switch g.Type {
+		case "Polygon":
+			var p [][]int
+			if err := json.Unmarshal(g.Arcs, &p); err != nil {
+				return nil, fmt.Errorf("ward-boundaries: ward %d: %w", g.Id, err)
+			}
+			polygons = [][][]int{p}
+		case "MultiPolygon":
+			if err := json.Unmarshal(g.Arcs, &polygons); err != nil {
+				return nil, fmt.Errorf("ward-boundaries: ward %d: %w", g.Id, err)
+			}
+		default:
+			continue
+		}
+
+		b := &WardBoundary{WardId: g.Id}
+		for _, p := range polygons {
+			polygon := Polygon{}
+			for _, indexes := range p {
+				r, err := ring(indexes)
+				if err != nil {
+					return nil, err
+				}
+				polygon = append(polygon, r)
+			}
+			b.MultiPolygon = append(b.MultiPolygon, polygon)
+		}
+		b.BBox = b.MultiPolygon.BBox()
+
+		if _, exists := boundaries[g.Id]; exists {
+			return nil, fmt.Errorf("ward-boundaries: duplicated ward %d", g.Id)
+		}
+		boundaries[g.Id] = b
+	}
+
+	return boundaries, nil
+}
+
+// Position is a [longitude, latitude] pair as in GeoJSON.
+type Position [2]float64
+
+type Ring []Position
+
+// Polygon is an outer ring followed by its holes.
+type Polygon []Ring
+
+type MultiPolygon []Polygon
+
+type BBox [4]float64 // min lon, min lat, max lon, max lat
+
+func (mp MultiPolygon) BBox() BBox {
+	b := BBox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range mp {
+		for _, r := range p {
+			for _, pos := range r {
+				b[0] = math.Min(b[0], pos[0])
+				b[1] = math.Min(b[1], pos[1])
+				b[2] = math.Max(b[2], pos[0])
+				b[3] = math.Max(b[3], pos[1])
+			}
+		}
+	}
+
+	return b
+}
+
+type WardBoundaries map[uint64]*WardBoundary // uint64: WardId
+
+type WardBoundary struct {
+	WardId       uint64
+	MultiPolygon MultiPolygon
+	BBox         BBox
+}
+
+type GeoJSONFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []*GeoJSONFeature `json:"features"`
+}
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type GeoJSONGeometry struct {
+	Type        string       `json:"type"`
+	Coordinates MultiPolygon `json:"coordinates"`
+}
+
+// ConstituencyGeometries are the ward boundaries of a campaign grouped by
+// constituency and encoded once when the campaign is loaded. Wards are not
+// dissolved; each constituency is a MultiPolygon of its wards.
+type ConstituencyGeometries struct {
+	ids        []uint64                   // sorted
+	geometries map[uint64]json.RawMessage // uint64: ConstituencyId
+}
+
+func NewConstituencyGeometries(boundaries WardBoundaries, idx *CampaignIndex) (*ConstituencyGeometries, error) {
+	wardIds := make([]uint64, 0, len(boundaries))
+	for wardId := range boundaries {
+		wardIds = append(wardIds, wardId)
+	}
+	sort.Slice(wardIds, func(i, j int) bool { return wardIds[i] < wardIds[j] })
+
+	grouped := map[uint64]*GeoJSONGeometry{}
+	for _, wardId := range wardIds {
+		constituencyId, exists := idx.ConstituencyOfWard(wardId)
+		if !exists {
+			continue
+		}
+
+		g, exists := grouped[constituencyId]
+		if !exists {
+			g = &GeoJSONGeometry{Type: "MultiPolygon"}
+			grouped[constituencyId] = g
+		}
+
+		g.Coordinates = append(g.Coordinates, boundaries[wardId].MultiPolygon...)
+	}
+
+	cg := &ConstituencyGeometries{
+		ids:        make([]uint64, 0, len(grouped)),
+		geometries: map[uint64]json.RawMessage{},
+	}
+	for id, g := range grouped {
+		data, err := json.Marshal(g)
+		if err != nil {
+			return nil, fmt.Errorf("ward-boundaries: constituency %d: %w", id, err)
+		}
+
+		cg.ids = append(cg.ids, id)
+		cg.geometries[id] = data
+	}
+	sort.Slice(cg.ids, func(i, j int) bool { return cg.ids[i] < cg.ids[j] })
+
+	return cg, nil
+}
+
+// ConstituencyFeature returns the feature of a constituency with its current
+// recall status, or nil if it has no boundaries.
+func (r Campaign) ConstituencyFeature(constituencyId uint64) *GeoJSONFeature {
+	geometry, exists := r.ConstituencyGeometries.geometries[constituencyId]
+	if !exists {
+		return nil
+	}
+
+	return &GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   geometry,
+		Properties: r.constituencyProperties(constituencyId),
+	}
+}
+
+// ConstituencyFeatures returns the features of every constituency with
+// boundaries, ordered by constituency id.
+func (r Campaign) ConstituencyFeatures() []*GeoJSONFeature {
+	features := make([]*GeoJSONFeature, 0, len(r.ConstituencyGeometries.ids))
+	for _, id := range r.ConstituencyGeometries.ids {
+		features = append(features, r.ConstituencyFeature(id))
+	}
+
+	return features
+}
+
+func (r Campaign) constituencyProperties(constituencyId uint64) map[string]interface{} {
+	props := map[string]interface{}{
+		"constituencyId": constituencyId,
+	}
+
+	rls := r.RecallLegislatorMap[constituencyId]
+	if len(rls) == 0 {
+		return props
+	}
+
+	l := rls[0]
+	props["constituencyCode"] = l.ConstituencyCode
+	props["constituencyName"] = l.ConstituencyName
+	props["politicianName"] = l.PoliticianName
+	props["recallStatus"] = l.RecallStatus
+	props["recallStage"] = l.RecallStage
+	props["participateURL"] = l.ParticipateURLString
+
+	return props
+}
+
+// MaxLocateDistance is how far, in metres, a position outside every ward may
+// be from the nearest ward boundary and still be matched to it. Simplified
+// boundaries leave gaps along coastlines and rivers.
+const MaxLocateDistance = 300.0
+
+const earthRadius = 6371008.8
+
+// Contains reports whether pos lies inside the polygon using the even-odd
+// rule, so positions inside a hole are outside.
+func (p Polygon) Contains(pos Position) bool {
+	inside := false
+	for _, r := range p {
+		for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+			a, b := r[i], r[j]
+			if (a[1] > pos[1]) != (b[1] > pos[1]) &&
+				pos[0] < (b[0]-a[0])*(pos[1]-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+func (mp MultiPolygon) Contains(pos Position) bool {
+	for _, p := range mp {
+		if p.Contains(pos) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Distance returns the approximate distance in metres from pos to the nearest
+// edge of the multipolygon, using an equirectangular projection around pos.
+func (mp MultiPolygon) Distance(pos Position) float64 {
+	kx := earthRadius * math.Pi / 180 * math.Cos(pos[1]*math.Pi/180)
+	ky := earthRadius * math.Pi / 180
+
+	shortest := math.Inf(1)
+	for _, p := range mp {
+		for _, r := range p {
+			for i := 1; i < len(r); i++ {
+				ax, ay := (r[i-1][0]-pos[0])*kx, (r[i-1][1]-pos[1])*ky
+				bx, by := (r[i][0]-pos[0])*kx, (r[i][1]-pos[1])*ky
+				shortest = math.Min(shortest, segmentDistance(ax, ay, bx, by))
+			}
+		}
+	}
+
+	return shortest
+}
+
+// segmentDistance returns the distance from the origin to segment AB.
+func segmentDistance(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+func (b BBox) Contains(pos Position, margin float64) bool {
+	return pos[0] >= b[0]-margin && pos[0] <= b[2]+margin &&
+		pos[1] >= b[1]-margin && pos[1] <= b[3]+margin
+}
+
+// LocateWard finds the ward containing pos. When no ward contains it, the
+// nearest ward within MaxLocateDistance is returned with exact set to false.
+func (r Campaign) LocateWard(pos Position) (loc *WardLocation, exact bool) {
+	// degrees of latitude covering MaxLocateDistance, widened for longitude
+	margin := MaxLocateDistance / (earthRadius * math.Pi / 180) * 2
+
+	var nearest *WardBoundary
+	nearestDistance := MaxLocateDistance
+	for _, b := range r.WardBoundaries {
+		if !b.BBox.Contains(pos, margin) {
+			continue
+		}
+
+		if b.BBox.Contains(pos, 0) && b.MultiPolygon.Contains(pos) {
+			if loc := r.Ward(b.WardId); loc != nil {
+				return loc, true
+			}
+		}
+
+		if d := b.MultiPolygon.Distance(pos); d <= nearestDistance {
+			nearest, nearestDistance = b, d
+		}
+	}
+
+	if nearest == nil {
+		return nil, false
+	}
+
+	return r.Ward(nearest.WardId), false
+}
@@ -1,3 +1,11 @@
 module github.com/imtaiwanese18741130/recall-2025
 
 go 1.23.2
+
+require (
+	golang.org/x/crypto v0.38.0
+	golang.org/x/text v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// JSONConfigLocalesDir, in the data directory, holds one flat message
+// catalog per locale, named <locale>.json. The catalog of DefaultLocale must
+// define every key; only PartialLocales may miss some, falling back to it.
+const JSONConfigLocalesDir = "locales"
+
+const (
+	LocaleZhTW  = "zh-TW"
+	LocaleEn    = "en"
+	LocaleNanTW = "nan-TW" // Taiwanese Hokkien
+	LocaleHakTW = "hak-TW" // Hakka
+
+	DefaultLocale = LocaleZhTW
+)
+
+// Locales lists the served locales in order of preference when nothing in
+// the request matches. Their catalogs must define every key of DefaultLocale.
+var Locales = []string{LocaleZhTW, LocaleEn}
+
+// PartialLocales are being translated. Their catalogs are read and checked
+// for unknown keys, but they are not negotiated or listed in the language
+// switcher until they move to Locales.
+var PartialLocales = []string{LocaleNanTW, LocaleHakTW}
+
+const LocaleCookieName = "lang"
+
+// Message keys of errors shown to users. Templates translate them with T.
+const (
+	MsgPageNotFound           = "error.page_not_found"
+	MsgNotPetitioning         = "error.not_petitioning"
+	MsgInvalidInput           = "error.invalid_input"
+	MsgInvalidIdNumber        = "error.invalid_id_number"
+	MsgInvalidMobileNumber    = "error.invalid_mobile_number"
+	MsgVotingDateNotAnnounced = "error.voting_date_not_announced"
+	MsgBadRequest             = "error.bad_request"
+	MsgVerificationFailed     = "error.verification_failed"
+	MsgInvalidRequest         = "error.invalid_request"
+)
+
+var localeMatcher = language.NewMatcher(localeTags())
+
+func localeTags() []language.Tag {
+	tags := make([]language.Tag, len(Locales))
+	for i, l := range Locales {
+		tags[i] = language.MustParse(l)
+	}
+	return tags
+}
+
+// config: locales
+func ReadConfigCatalogs(dir string) (Catalogs, error) {
+	catalogs := Catalogs{}
+	for _, l := range append(append([]string{}, Locales...), PartialLocales...) {
+		file, err := os.Open(filepath.Join(dir, l+".json"))
+		if err != nil {
+			return nil, err
+		}
+
+		messages := map[string]string{}
+
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(&messages)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("locales: %s: %w", l, err)
+		}
+
+		catalogs[l] = messages
+	}
+
+	for l, messages := range catalogs {
+		for key := range messages {
+			if _, exists := catalogs[DefaultLocale][key]; !exists {
+				return nil, fmt.Errorf("locales: %s: key %q is not defined in %s", l, key, DefaultLocale)
+			}
+		}
+	}
+
+	return catalogs, nil
+}
+
+type Catalogs map[string]map[string]string // string: locale
+
+// Message returns the message of key in locale, falling back to DefaultLocale
+// and then to the key itself, with {name} placeholders replaced by args given
+// as name/value pairs. time.Time values are written as ROC dates.
+func (c Catalogs) Message(locale, key string, args ...interface{}) string {
+	msg, exists := c[locale][key]
+	if !exists {
+		if msg, exists = c[DefaultLocale][key]; !exists {
+			msg = key
+		}
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		arg := indirectArg(args[i+1])
+		value := fmt.Sprint(arg)
+		if t, ok := arg.(time.Time); ok {
+			value = c.FormatROCDate(locale, t)
+		}
+		pairs = append(pairs, "{"+fmt.Sprint(args[i])+"}", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// indirectArg dereferences pointer arguments, such as optional *string config
+// fields, the way templates print them. Nil pointers become "".
+func indirectArg(arg interface{}) interface{} {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Pointer {
+		return arg
+	}
+	if v.IsNil() {
+		return ""
+	}
+
+	return v.Elem().Interface()
+}
+
+// Messages returns the messages whose key starts with prefix, for scripts that
+// render text on the client.
+func (c Catalogs) Messages(locale, prefix string) map[string]string {
+	messages := map[string]string{}
+	for _, l := range []string{DefaultLocale, locale} {
+		for key, msg := range c[l] {
+			if strings.HasPrefix(key, prefix) {
+				messages[strings.TrimPrefix(key, prefix)] = msg
+			}
+		}
+	}
+
+	return messages
+}
+
+// FormatDate renders a date as month and day, e.g. "5 月 3 日" or "May 3".
+// It accepts the values toTime does and returns "" for empty dates.
+func (c Catalogs) FormatDate(locale string, date interface{}) string {
+	t, ok := toTime(date)
+	if !ok {
+		return ""
+	}
+
+	return c.Message(locale, "format.month_day", c.dateArgs(locale, t)...)
+}
+
+// FormatROCDate renders a full date with its ROC year, e.g.
+// "民國 114 年 5 月 3 日".
+func (c Catalogs) FormatROCDate(locale string, date interface{}) string {
+	t, ok := toTime(date)
+	if !ok {
+		return ""
+	}
+
+	return c.Message(locale, "format.roc_date", c.dateArgs(locale, t)...)
+}
+
+func (c Catalogs) dateArgs(locale string, t time.Time) []interface{} {
+	return []interface{}{
+		"year", t.Year(),
+		"rocYear", NewROCDate(t).Year,
+		"month", int(t.Month()),
+		"monthName", c.Message(locale, "format.month."+strconv.Itoa(int(t.Month()))),
+		"day", t.Day(),
+	}
+}
+
+// FuncMap binds the template functions to locale. Each locale gets its own
+// template set so templates can call T without passing the locale around.
+func (c Catalogs) FuncMap(locale string) template.FuncMap {
+	return template.FuncMap{
+		"T": func(key string, args ...interface{}) string {
+			return c.Message(locale, key, args...)
+		},
+		// TH is T for catalog entries containing markup. Catalogs are part of
+		// the trusted configuration; string arguments are escaped.
+		"TH": func(key string, args ...interface{}) template.HTML {
+			escaped := make([]interface{}, len(args))
+			for i, arg := range args {
+				if s, ok := indirectArg(arg).(string); ok && i%2 == 1 {
+					arg = template.HTMLEscapeString(s)
+				}
+				escaped[i] = arg
+			}
+
+			return template.HTML(c.Message(locale, key, escaped...))
+		},
+		"date": func(date interface{}) string {
+			return c.FormatDate(locale, date)
+		},
+		"rocDate": func(date interface{}) string {
+			return c.FormatROCDate(locale, date)
+		},
+		"rocYear": func(date interface{}) int {
+			t, ok := toTime(date)
+			if !ok {
+				return 0
+			}
+			return NewROCDate(t).Year
+		},
+		"messages": func(prefix string) map[string]string {
+			return c.Messages(locale, prefix)
+		},
+		"locale": func() string {
+			return locale
+		},
+		"locales": func() []string {
+			return Locales
+		},
+		"lang": func() string {
+			return c.Message(locale, "meta.html_lang")
+		},
+	}
+}
+
+// ParseTemplates parses the templates matching pattern once per locale.
+func ParseTemplates(catalogs Catalogs, pattern string) (map[string]*template.Template, error) {
+	tmpls := map[string]*template.Template{}
+	for _, l := range Locales {
+		t, err := template.New("").Funcs(catalogs.FuncMap(l)).ParseGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		tmpls[l] = t
+	}
+
+	return tmpls, nil
+}
+
+// MatchLocale returns the supported locale closest to the given language
+// tags or Accept-Language values, or DefaultLocale when nothing matches.
+func MatchLocale(values ...string) string {
+	tags := []language.Tag{}
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+
+		ts, _, err := language.ParseAcceptLanguage(v)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, ts...)
+	}
+
+	if len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, i, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+
+	return Locales[i]
+}
+
+// Locale negotiates the locale of a request: the lang parameter first, which
+// is remembered in a cookie, then the cookie, then Accept-Language.
+func (ctrl *Controller) Locale(w http.ResponseWriter, r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		locale := MatchLocale(lang)
+		http.SetCookie(w, &http.Cookie{
+			Name:     LocaleCookieName,
+			Value:    locale,
+			Path:     "/",
+			MaxAge:   365 * 24 * 60 * 60,
+			HttpOnly: true,
+			Secure:   ctrl.AppBaseURL.Scheme == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+		return locale
+	}
+
+	if cookie, err := r.Cookie(LocaleCookieName); err == nil {
+		return MatchLocale(cookie.Value)
+	}
+
+	return MatchLocale(r.Header.Get("Accept-Language"))
+}
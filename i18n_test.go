@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestServedCatalogsComplete(t *testing.T) {
+	catalogs, err := ReadConfigCatalogs(filepath.Join("json-config", JSONConfigLocalesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, l := range Locales {
+		missing := []string{}
+		for key := range catalogs[DefaultLocale] {
+			if _, exists := catalogs[l][key]; !exists {
+				missing = append(missing, key)
+			}
+		}
+		sort.Strings(missing)
+
+		if len(missing) > 0 {
+			t.Errorf("%s is served but misses %d keys of %s: %v", l, len(missing), DefaultLocale, missing)
+		}
+	}
+}
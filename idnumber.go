@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+)
+
+type IdNumberType string
+
+const (
+	// IdNumberTypeNational is the national ID of ROC citizens.
+	IdNumberTypeNational IdNumberType = "national"
+	// IdNumberTypeUniform is the Uniform ID issued to foreign residents since
+	// 2021, shaped like a national ID with 8 or 9 as the second digit.
+	IdNumberTypeUniform IdNumberType = "uniform"
+	// IdNumberTypeLegacyARC is the resident certificate number issued before
+	// 2021, with a second letter from A to D.
+	IdNumberTypeLegacyARC IdNumberType = "legacy-arc"
+)
+
+// PetitionIdNumberTypes are the ID types whose holders may sign recall
+// petitions. Only citizens have the right to vote.
+var PetitionIdNumberTypes = []IdNumberType{IdNumberTypeNational}
+
+type IdNumberReason string
+
+const (
+	IdNumberBadLength   IdNumberReason = "bad_length"
+	IdNumberBadLetter   IdNumberReason = "bad_letter"
+	IdNumberBadDigit    IdNumberReason = "bad_digit"
+	IdNumberBadChecksum IdNumberReason = "bad_checksum"
+	IdNumberUnknownType IdNumberReason = "unknown_type"
+	IdNumberNotEligible IdNumberReason = "not_eligible"
+)
+
+type IdNumberError struct {
+	Type   IdNumberType
+	Reason IdNumberReason
+}
+
+func (e *IdNumberError) Error() string {
+	if e.Type == "" {
+		return "id number: " + string(e.Reason)
+	}
+	return "id number: " + string(e.Type) + ": " + string(e.Reason)
+}
+
+// MessageKey returns the catalog key explaining the error to users.
+func (e *IdNumberError) MessageKey() string {
+	return "error.id_number." + string(e.Reason)
+}
+
+// IdNumberValidator validates one type of ID number. Match only looks at the
+// shape of the number to pick the validator; Validate does the full check.
+type IdNumberValidator interface {
+	Type() IdNumberType
+	Match(id string) bool
+	Validate(id string) error
+}
+
+var IdNumberValidators = []IdNumberValidator{
+	nationalIdNumberValidator{},
+	uniformIdNumberValidator{},
+	legacyARCIdNumberValidator{},
+}
+
+// ValidateIdNumber finds the validator matching id and returns its type. id is
+// expected to be normalized by normalizeIdNumber.
+func ValidateIdNumber(id string) (IdNumberType, error) {
+	if len(id) != 10 {
+		return "", &IdNumberError{Reason: IdNumberBadLength}
+	}
+
+	if _, ok := idNumberLetters[id[0]]; !ok {
+		return "", &IdNumberError{Reason: IdNumberBadLetter}
+	}
+
+	for _, v := range IdNumberValidators {
+		if v.Match(id) {
+			return v.Type(), v.Validate(id)
+		}
+	}
+
+	return "", &IdNumberError{Reason: IdNumberUnknownType}
+}
+
+// ValidatePetitionIdNumber accepts only the ID types in PetitionIdNumberTypes.
+func ValidatePetitionIdNumber(id string) error {
+	t, err := ValidateIdNumber(id)
+	if err != nil {
+		return err
+	}
+
+	for _, eligible := range PetitionIdNumberTypes {
+		if t == eligible {
+			return nil
+		}
+	}
+
+	return &IdNumberError{Type: t, Reason: IdNumberNotEligible}
+}
+
+// idNumberMessageKey returns the catalog key of an error from
+// ValidatePetitionIdNumber.
+func idNumberMessageKey(err error) string {
+	var idErr *IdNumberError
+	if errors.As(err, &idErr) {
+		return idErr.MessageKey()
+	}
+
+	return MsgInvalidIdNumber
+}
+
+// idNumberLetters maps the leading letter, which encodes where the number was
+// issued, to its two-digit code.
+var idNumberLetters = map[byte]int{
+	'A': 10, 'B': 11, 'C': 12, 'D': 13, 'E': 14,
+	'F': 15, 'G': 16, 'H': 17, 'J': 18, 'K': 19,
+	'L': 20, 'M': 21, 'N': 22, 'P': 23, 'Q': 24,
+	'R': 25, 'S': 26, 'T': 27, 'U': 28, 'V': 29,
+	'X': 30, 'Y': 31, 'W': 32, 'Z': 33, 'I': 34,
+	'O': 35,
+}
+
+var idNumberWeights = []int{1, 9, 8, 7, 6, 5, 4, 3, 2, 1, 1}
+
+// idNumberChecksum validates the checksum shared by every ID type. second is
+// the value of the second character, which differs between types; the last
+// eight characters must be digits.
+func idNumberChecksum(t IdNumberType, id string, second int) error {
+	letter := idNumberLetters[id[0]]
+	digits := []int{letter / 10, letter % 10, second}
+
+	for i := 2; i < 10; i++ {
+		if id[i] < '0' || id[i] > '9' {
+			return &IdNumberError{Type: t, Reason: IdNumberBadDigit}
+		}
+		digits = append(digits, int(id[i]-'0'))
+	}
+
+	sum := 0
+	for i, d := range digits {
+		sum += d * idNumberWeights[i]
+	}
+
+	if sum%10 != 0 {
+		return &IdNumberError{Type: t, Reason: IdNumberBadChecksum}
+	}
+
+	return nil
+}
+
+type nationalIdNumberValidator struct{}
+
+func (nationalIdNumberValidator) Type() IdNumberType { return IdNumberTypeNational }
+
+// The second digit is the sex: 1 or 2.
+func (nationalIdNumberValidator) Match(id string) bool {
+	return id[1] == '1' || id[1] == '2'
+}
+
+func (v nationalIdNumberValidator) Validate(id string) error {
+	return idNumberChecksum(v.Type(), id, int(id[1]-'0'))
+}
+
+type uniformIdNumberValidator struct{}
+
+func (uniformIdNumberValidator) Type() IdNumberType { return IdNumberTypeUniform }
+
+// The second digit is the sex: 8 or 9.
+func (uniformIdNumberValidator) Match(id string) bool {
+	return id[1] == '8' || id[1] == '9'
+}
+
+func (v uniformIdNumberValidator) Validate(id string) error {
+	return idNumberChecksum(v.Type(), id, int(id[1]-'0'))
+}
+
+type legacyARCIdNumberValidator struct{}
+
+func (legacyARCIdNumberValidator) Type() IdNumberType { return IdNumberTypeLegacyARC }
+
+func (legacyARCIdNumberValidator) Match(id string) bool {
+	return id[1] >= 'A' && id[1] <= 'D'
+}
+
+// The second letter counts as the last digit of its letter code.
+func (v legacyARCIdNumberValidator) Validate(id string) error {
+	return idNumberChecksum(v.Type(), id, idNumberLetters[id[1]]%10)
+}
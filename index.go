@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+)
+
+// CampaignIndex holds the lookup tables of a campaign. It is built once when
+// the campaign is loaded and never mutated afterwards, so handlers can read it
+// without locking.
+type CampaignIndex struct {
+	legislatorsByCode         map[string]*RecallLegislator
+	legislatorsByMunicipality map[uint64]RecallLegislators // uint64: MunicipalityId
+	municipalities            map[uint64]*Municipality
+	districts                 map[uint64]*DistrictLocation
+	wards                     map[uint64]*WardLocation
+	districtsWithLegislators  map[uint64]bool // uint64: DistrictId
+}
+
+type DistrictLocation struct {
+	Municipality *Municipality
+	District     *District
+}
+
+type WardLocation struct {
+	Municipality *Municipality
+	District     *District
+	Ward         *Ward
+}
+
+func NewCampaignIndex(rs RecallLegislators, ms Municipalities) (*CampaignIndex, error) {
+	idx := &CampaignIndex{
+		legislatorsByCode:         map[string]*RecallLegislator{},
+		legislatorsByMunicipality: map[uint64]RecallLegislators{},
+		municipalities:            map[uint64]*Municipality{},
+		districts:                 map[uint64]*DistrictLocation{},
+		wards:                     map[uint64]*WardLocation{},
+		districtsWithLegislators:  map[uint64]bool{},
+	}
+
+	constituencies := map[uint64]bool{}
+	for _, r := range rs {
+		idx.legislatorsByCode[r.ConstituencyCode] = r
+		idx.legislatorsByMunicipality[r.MunicipalityId] = append(idx.legislatorsByMunicipality[r.MunicipalityId], r)
+		constituencies[r.ConstituencyId] = true
+	}
+
+	for _, m := range ms {
+		if _, exists := idx.municipalities[m.Id]; exists {
+			return nil, fmt.Errorf("administrative-divisions: duplicated municipality %d", m.Id)
+		}
+		idx.municipalities[m.Id] = m
+
+		for _, d := range m.Districts {
+			if _, exists := idx.districts[d.Id]; exists {
+				return nil, fmt.Errorf("administrative-divisions: duplicated district %d", d.Id)
+			}
+			idx.districts[d.Id] = &DistrictLocation{m, d}
+
+			for _, w := range d.Wards {
+				if _, exists := idx.wards[w.Id]; exists {
+					return nil, fmt.Errorf("administrative-divisions: duplicated ward %d", w.Id)
+				}
+				idx.wards[w.Id] = &WardLocation{m, d, w}
+
+				if constituencies[w.ConstituencyId] {
+					idx.districtsWithLegislators[d.Id] = true
+				}
+			}
+		}
+	}
+
+	return idx, nil
+}
+
+func (idx *CampaignIndex) LegislatorByCode(code string) *RecallLegislator {
+	return idx.legislatorsByCode[code]
+}
+
+func (idx *CampaignIndex) LegislatorsInMunicipality(municipalityId uint64) RecallLegislators {
+	return idx.legislatorsByMunicipality[municipalityId]
+}
+
+func (idx *CampaignIndex) Municipality(municipalityId uint64) *Municipality {
+	return idx.municipalities[municipalityId]
+}
+
+func (idx *CampaignIndex) District(districtId uint64) *DistrictLocation {
+	return idx.districts[districtId]
+}
+
+func (idx *CampaignIndex) Ward(wardId uint64) *WardLocation {
+	return idx.wards[wardId]
+}
+
+// ConstituencyOfWard returns the constituency a ward votes in.
+func (idx *CampaignIndex) ConstituencyOfWard(wardId uint64) (uint64, bool) {
+	loc, exists := idx.wards[wardId]
+	if !exists {
+		return 0, false
+	}
+
+	return loc.Ward.ConstituencyId, true
+}
+
+func (idx *CampaignIndex) DistrictHasLegislators(districtId uint64) bool {
+	return idx.districtsWithLegislators[districtId]
+}
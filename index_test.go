@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func readTestCampaign(tb testing.TB) *Campaign {
+	tb.Helper()
+
+	variants, err := ReadConfigNameVariants("json-config")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	baseURL, _ := url.Parse("http://localhost:8080")
+	c, err := ReadCampaign(filepath.Join("json-config", JSONConfigTermsDir, "11"), 11, baseURL, variants)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	c.Current = true
+
+	return c
+}
+
+func BenchmarkFindRecallLegislators(b *testing.B) {
+	c := readTestCampaign(b)
+
+	type query struct{ municipalityId, districtId, wardId uint64 }
+	queries := []query{}
+	for _, loc := range c.wards {
+		if len(c.RecallLegislatorMap[loc.Ward.ConstituencyId]) > 0 {
+			queries = append(queries, query{loc.Municipality.Id, loc.District.Id, loc.Ward.Id})
+		}
+	}
+	if len(queries) == 0 {
+		b.Fatal("no wards with recall legislators")
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := queries[i%len(queries)]
+		if _, rls, err := c.FindRecallLegislators(q.municipalityId, &q.districtId, &q.wardId); err != nil || len(rls) == 0 {
+			b.Fatalf("%+v: %v", q, err)
+		}
+	}
+}
+
+func BenchmarkGetRecallLegislator(b *testing.B) {
+	c := readTestCampaign(b)
+
+	b.Run("name", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			l := c.RecallLegislators[i%len(c.RecallLegislators)]
+			if c.GetRecallLegislator(l.PoliticianName) != l {
+				b.Fatalf("%s not found", l.PoliticianName)
+			}
+		}
+	})
+
+	b.Run("code", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			l := c.RecallLegislators[i%len(c.RecallLegislators)]
+			if c.GetRecallLegislatorByCode(l.ConstituencyCode) != l {
+				b.Fatalf("%s not found", l.ConstituencyCode)
+			}
+		}
+	})
+}
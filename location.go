@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "time/tzdata" // fallback when the host has no tz database
+)
+
+// TimeZone is where every campaign runs: deadlines, voting hours and ages
+// are all in Taiwan time.
+const TimeZone = "Asia/Taipei"
+
+// Location is TimeZone, loaded once at startup; LocationSource tells where it
+// was loaded from.
+var Location, LocationSource = loadLocation(TimeZone)
+
+// systemZoneinfoDirs are where Unix hosts keep the tz database.
+var systemZoneinfoDirs = []string{
+	"/usr/share/zoneinfo",
+	"/usr/share/lib/zoneinfo",
+	"/usr/lib/locale/TZ",
+	"/etc/zoneinfo",
+}
+
+// loadLocation prefers the host's tz database, which gets updates with the
+// OS, then time.LoadLocation, which tries $ZONEINFO, $GOROOT and the copy
+// embedded by time/tzdata. Taiwan has had no DST since 1979, so a fixed
+// UTC+8 is a safe last resort.
+func loadLocation(name string) (*time.Location, string) {
+	for _, dir := range systemZoneinfoDirs {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		if loc, err := time.LoadLocationFromTZData(name, data); err == nil {
+			return loc, path
+		}
+	}
+
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, "time.LoadLocation (ZONEINFO/GOROOT/embedded)"
+	}
+
+	return time.FixedZone(name, 8*60*60), "fixed UTC+8"
+}
+
+// CheckLocation logs where Location was loaded from and warns if it is not
+// UTC+8 now.
+func CheckLocation(now time.Time) {
+	zone, offset := now.In(Location).Zone()
+	log.Printf("Time zone %s (%s, UTC%+d) loaded from %s", TimeZone, zone, offset/3600, LocationSource)
+
+	if offset != 8*60*60 {
+		log.Printf("Time zone %s is UTC%+d instead of UTC+8; check %s", TimeZone, offset/3600, LocationSource)
+	}
+}
@@ -1,39 +1,86 @@
 package main
 
 import (
-	"html/template"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
 func main() {
-	cfg, err := LoadConfig()
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1:]))
 	}
 
-	tmpl, err := template.ParseGlob("templates/*.html")
+	settings, printConfig, err := LoadSettings(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		panic("template parse error: " + err.Error())
+		fmt.Fprintln(os.Stderr, "settings error:", err)
+		os.Exit(2)
+	}
+
+	if printConfig {
+		if err := settings.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	ctrl := NewController(cfg, tmpl)
-	if err := ctrl.CalcDaysLeft(); err != nil {
-		panic("calc days left error: " + err.Error())
+	CheckLocation(time.Now())
+
+	app, err := NewApp(settings)
+	if err != nil {
+		panic(err)
 	}
+
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
 		defer ticker.Stop()
 
 		for {
-			if err := ctrl.CalcDaysLeft(); err != nil {
-				log.Println("CalcDaysLeft error:", err)
-			}
 			<-ticker.C
+			if err := app.ApplyTransitions(time.Now()); err != nil {
+				log.Println("ApplyTransitions error:", err)
+			}
 		}
 	}()
 
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+
+		for range hup {
+			if err := app.Reload("SIGHUP"); err != nil {
+				log.Println("Reload error:", err)
+				continue
+			}
+			log.Println("Reloaded json-config and templates")
+		}
+	}()
+
+	srv := &http.Server{
+		Addr:         ":" + settings.Port,
+		Handler:      logRequest(app),
+		ReadTimeout:  settings.ReadTimeout,
+		WriteTimeout: settings.WriteTimeout,
+		IdleTimeout:  settings.IdleTimeout,
+	}
+
+	log.Printf("Listening on port %s", settings.Port)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (a *App) routes(ctrl *Controller) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health/v1/ping", withRecovery(ctrl.Ping))
@@ -44,24 +91,35 @@ func main() {
 	mux.HandleFunc("/", withRecovery(ctrl.Home))
 	mux.HandleFunc("/authorization-letter", withRecovery(ctrl.AuthorizationLetter))
 	mux.HandleFunc("/apis/constituencies", withRecovery(ctrl.SearchRecallConstituency))
+	mux.HandleFunc("/apis/polling-stations", withRecovery(ctrl.SearchPollingStations))
+	mux.HandleFunc("/apis/results", withRecovery(ctrl.SearchRecallResults))
+	mux.HandleFunc("/apis/maps/constituencies", withRecovery(ctrl.ConstituencyMap))
+	mux.HandleFunc("/apis/maps/constituencies/", withRecovery(ctrl.ConstituencyMap))
+	mux.HandleFunc("/apis/locate", withRecovery(ctrl.LocateWard))
+	mux.HandleFunc("/apis/eligibility", withRecovery(ctrl.CheckEligibility))
+	mux.HandleFunc("/eligibility", withRecovery(ctrl.EligibilityPage))
 	mux.HandleFunc("/preview/stages/", withRecovery(ctrl.PreviewOriginalLocalForm))
 	mux.HandleFunc("/legislators/", withRecovery(ctrl.LegislatorRouter))
+	mux.HandleFunc("/c/", withRecovery(ctrl.ConstituencyRouter))
+	mux.HandleFunc("/terms/", withRecovery(ctrl.TermRouter))
 	mux.HandleFunc("/mayor", withRecovery(ctrl.MParticipate))
 	mux.HandleFunc("/mayor/preview", withRecovery(ctrl.MPreviewLocalForm))
 	mux.HandleFunc("/mayor/thank-you", withRecovery(ctrl.MThankYou))
 
-	srv := &http.Server{
-		Addr:         ":" + cfg.AppPort,
-		Handler:      logRequest(mux),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+	if ctrl.AppEnv == AppEnvDev {
+		mux.HandleFunc("/admin/form-layouts", withRecovery(ctrl.FormLayoutsAdmin))
+		mux.HandleFunc("/admin/form-layouts/", withRecovery(ctrl.FormLayoutsAdmin))
+		mux.HandleFunc("/apis/admin/form-layouts/", withRecovery(ctrl.SaveFormLayout))
 	}
 
-	log.Printf("Listening on port %s", cfg.AppPort)
-	if err := srv.ListenAndServe(); err != nil {
-		log.Fatal(err)
-	}
+	console := &AdminConsole{Controller: ctrl, app: a}
+	mux.HandleFunc("/admin", withRecovery(console.Home))
+	mux.HandleFunc("/admin/login", withRecovery(console.Login))
+	mux.HandleFunc("/admin/logout", withRecovery(console.Logout))
+	mux.HandleFunc("/apis/admin/legislators/", withRecovery(console.UpdateLegislator))
+	mux.HandleFunc("/apis/admin/audit", withRecovery(console.AuditLog))
+
+	return mux
 }
 
 func withRecovery(h http.HandlerFunc) http.HandlerFunc {
@@ -3,109 +3,46 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
-)
-
-func isValidMobileNumber(mobile string) bool {
-	return regexp.MustCompile(`^09\d{8}$`).MatchString(mobile)
-}
+	"unicode"
 
-func isValidIdNumber(text string) bool {
-	if len(text) != 10 {
-		return false
-	}
-
-	letterMap := map[string]int{
-		"A": 10, "B": 11, "C": 12, "D": 13, "E": 14,
-		"F": 15, "G": 16, "H": 17, "J": 18, "K": 19,
-		"L": 20, "M": 21, "N": 22, "P": 23, "Q": 24,
-		"R": 25, "S": 26, "T": 27, "U": 28, "V": 29,
-		"X": 30, "Y": 31, "W": 32, "Z": 33, "I": 34,
-		"O": 35,
-	}
-
-	firstLetter := string(text[0])
-	if _, ok := letterMap[firstLetter]; !ok {
-		return false
-	}
-
-	firstDigit := letterMap[firstLetter]
-	digits := []int{
-		firstDigit / 10,
-		firstDigit % 10,
-	}
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
 
-	for i := 1; i < 10; i++ {
-		num, err := strconv.Atoi(string(text[i]))
-		if err != nil {
-			return false
+// normalizeInput cleans up a form value typed on a Chinese IME or pasted from
+// chat apps: full-width letters, digits and spaces become half-width, while
+// CJK punctuation such as 「」、。 stays full-width and half-width forms of it
+// are widened back. Zero-width and other invisible format characters are
+// dropped, and runs of whitespace collapse to a single space.
+func normalizeInput(s string) string {
+	s = width.Fold.String(norm.NFC.String(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
 		}
-		digits = append(digits, num)
-	}
+		return r
+	}, s)
 
-	checksum := digits[0] + digits[1]*9 + digits[2]*8 + digits[3]*7 + digits[4]*6 + digits[5]*5 + digits[6]*4 + digits[7]*3 + digits[8]*2 + digits[9] + digits[10]
-	return checksum%10 == 0
+	return strings.Join(strings.Fields(s), " ")
 }
 
-var arabicToChinese = map[string]string{
-	"1":  "一",
-	"2":  "二",
-	"3":  "三",
-	"4":  "四",
-	"5":  "五",
-	"6":  "六",
-	"7":  "七",
-	"8":  "八",
-	"9":  "九",
-	"10": "十",
+// normalizeIdNumber removes the spaces people use to group the digits and
+// upper-cases the letters.
+func normalizeIdNumber(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(normalizeInput(s), " ", ""))
 }
 
-func sanitizeAddress(address string) string {
-	address = strings.ReplaceAll(address, "-", "之")
-
-	pattern := regexp.MustCompile(`(\d+)\s*(段|樓)`)
-	converted := pattern.ReplaceAllStringFunc(address, func(match string) string {
-		subMatch := pattern.FindStringSubmatch(match)
-		if len(subMatch) < 3 {
-			return match
-		}
-
-		arabic := subMatch[1]
-		unit := subMatch[2]
-
-		if chinese, ok := arabicToChinese[arabic]; ok {
-			return chinese + unit
-		}
-
-		if num := convertLargeNumber(arabic); num != "" {
-			return num + unit
-		}
-
-		return match
-	})
-
-	return converted
+// normalizeMobileNumber removes spaces and dashes, as in 0912-345-678.
+func normalizeMobileNumber(s string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(normalizeInput(s))
 }
 
-func convertLargeNumber(num string) string {
-	if n := len(num); n == 1 {
-		return arabicToChinese[num]
-	} else if n == 2 {
-		digits := strings.Split(num, "")
-		result := ""
-		if digits[0] == "1" {
-			result += "十"
-		} else {
-			result += arabicToChinese[digits[0]] + "十"
-		}
-		if digits[1] != "0" {
-			result += arabicToChinese[digits[1]]
-		}
-		return result
-	}
-	return ""
+func isValidMobileNumber(mobile string) bool {
+	return regexp.MustCompile(`^09\d{8}$`).MatchString(mobile)
 }
 
 func PrintStructWithJSON(strc interface{}) {
@@ -115,3 +52,30 @@ func PrintStructWithJSON(strc interface{}) {
 	}
 	fmt.Println(string(jsonData))
 }
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so readers never see a partly written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestNormalizeInput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "王小明", "王小明"},
+		{"full-width letters and digits", "Ａ１２３４５６７８９", "A123456789"},
+		{"full-width address digits", "中正路１００號", "中正路100號"},
+		{"zero width space", "王\u200b小明", "王小明"},
+		{"zero width joiner", "王小\u200d明", "王小明"},
+		{"byte order mark", "\ufeff王小明", "王小明"},
+		{"mixed whitespace", " 忠孝東路\t一段\u3000１號  ", "忠孝東路 一段 1號"},
+		{"CJK punctuation", "臺北市中正區，忠孝東路「一段」、１２號。", "臺北市中正區,忠孝東路「一段」、12號。"},
+		{"full-width commas", "１，２，３", "1,2,3"},
+		{"half-width CJK punctuation", "｢一段｣､12號｡", "「一段」、12號。"},
+		{"decomposed characters", "Cafe\u0301", "Caf\u00e9"},
+		{"empty", " \u200b ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeInput(tt.in); got != tt.want {
+				t.Errorf("normalizeInput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeIdNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "A123456789", "A123456789"},
+		{"lower-case letter", "a123456789", "A123456789"},
+		{"full-width", "ａ１２３４５６７８９", "A123456789"},
+		{"grouped digits", "A 123 456 789", "A123456789"},
+		{"ideographic spaces", "A\u3000123\u3000456789", "A123456789"},
+		{"zero width characters", "\ufeffA123\u200b456\u200d789", "A123456789"},
+		{"new resident certificate", "a8 0000 0001", "A800000001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeIdNumber(tt.in); got != tt.want {
+				t.Errorf("normalizeIdNumber(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMobileNumber(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		want  string
+		valid bool
+	}{
+		{"plain", "0912345678", "0912345678", true},
+		{"dashes", "0912-345-678", "0912345678", true},
+		{"spaces", "0912 345 678", "0912345678", true},
+		{"full-width", "０９１２－３４５－６７８", "0912345678", true},
+		{"mixed whitespace", "\t0912\u3000345 678 ", "0912345678", true},
+		{"zero width characters", "0912\u200b345\ufeff678", "0912345678", true},
+		{"landline", "02-2345-6789", "0223456789", false},
+		{"too short", "0912-345", "0912345", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeMobileNumber(tt.in)
+			if got != tt.want {
+				t.Errorf("normalizeMobileNumber(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if valid := isValidMobileNumber(got); valid != tt.valid {
+				t.Errorf("isValidMobileNumber(%q) = %v, want %v", got, valid, tt.valid)
+			}
+		})
+	}
+}
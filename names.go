@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const JSONConfigNameVariants = "name-variants.json"
+
+// config: name-variants
+//
+// Each group lists characters that people use interchangeably when typing or
+// sharing a politician's name. The first character of a group is the one
+// names are folded to.
+func ReadConfigNameVariants(dir string) (NameVariants, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigNameVariants))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	groups := [][]string{}
+
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&groups); err != nil {
+		return nil, err
+	}
+
+	variants := NameVariants{}
+	for _, g := range groups {
+		if len(g) < 2 {
+			return nil, fmt.Errorf("name-variants: group %v needs at least two characters", g)
+		}
+
+		target := []rune(norm.NFC.String(g[0]))
+		if len(target) != 1 {
+			return nil, fmt.Errorf("name-variants: %q is not a single character", g[0])
+		}
+
+		for _, c := range g[1:] {
+			src := []rune(norm.NFC.String(c))
+			if len(src) != 1 {
+				return nil, fmt.Errorf("name-variants: %q is not a single character", c)
+			}
+			if prev, exists := variants[src[0]]; exists && prev != target[0] {
+				return nil, fmt.Errorf("name-variants: %q belongs to more than one group", c)
+			}
+			variants[src[0]] = target[0]
+		}
+	}
+
+	return variants, nil
+}
+
+type NameVariants map[rune]rune
+
+// Fold normalizes name to NFC, trims it and replaces variant characters, so
+// that every spelling of the same name yields the same key.
+func (v NameVariants) Fold(name string) string {
+	name = strings.TrimSpace(norm.NFC.String(name))
+
+	return strings.Map(func(r rune) rune {
+		if t, exists := v[r]; exists {
+			return t
+		}
+		return r
+	}, name)
+}
+
+// NameIndex resolves politician names, including variant spellings and
+// explicit aliases, to legislators.
+type NameIndex struct {
+	variants NameVariants
+	folded   map[string]*RecallLegislator
+}
+
+func NewNameIndex(rs RecallLegislators, variants NameVariants) (*NameIndex, error) {
+	idx := &NameIndex{
+		variants: variants,
+		folded:   map[string]*RecallLegislator{},
+	}
+
+	for _, r := range rs {
+		for _, name := range append([]string{r.PoliticianName}, r.Aliases...) {
+			key := variants.Fold(name)
+			if prev, exists := idx.folded[key]; exists && prev != r {
+				return nil, fmt.Errorf("name %q is ambiguous between %s and %s", name, prev.PoliticianName, r.PoliticianName)
+			}
+			idx.folded[key] = r
+		}
+	}
+
+	return idx, nil
+}
+
+// Lookup returns the legislator matching name and whether name is already the
+// canonical spelling. Callers should redirect non-canonical hits.
+func (idx *NameIndex) Lookup(name string) (*RecallLegislator, bool) {
+	l, exists := idx.folded[idx.variants.Fold(name)]
+	if !exists {
+		return nil, false
+	}
+
+	return l, l.PoliticianName == name
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const JSONConfigRecallResults = "recall-results.json"
+
+// config: recall-results
+func ReadConfigRecallResults(dir string, municipalities Municipalities) (RecallResults, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigRecallResults))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows := []*RecallResult{}
+
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	wardNames := map[uint64]string{}
+	for _, m := range municipalities {
+		for _, d := range m.Districts {
+			for _, w := range d.Wards {
+				wardNames[w.Id] = d.Name + w.Name
+			}
+		}
+	}
+
+	results := RecallResults{}
+	for _, r := range rows {
+		if _, exists := results[r.ConstituencyId]; exists {
+			return nil, fmt.Errorf("recall-results: duplicated constituency %d", r.ConstituencyId)
+		}
+
+		r.Calc()
+		for _, w := range r.Wards {
+			w.Calc()
+			w.WardName = wardNames[w.WardId]
+		}
+		results[r.ConstituencyId] = r
+	}
+
+	return results, nil
+}
+
+type RecallResults map[uint64]*RecallResult // uint64: ConstituencyId
+
+type VoteCount struct {
+	AgreeVotes     uint64  `json:"agreeVotes"`
+	DisagreeVotes  uint64  `json:"disagreeVotes"`
+	ValidVotes     uint64  `json:"validVotes"`
+	InvalidVotes   uint64  `json:"invalidVotes"`
+	EligibleVoters uint64  `json:"eligibleVoters"`
+	Turnout        float64 `json:"turnout"`
+	AgreeRate      float64 `json:"agreeRate"`
+}
+
+func (v *VoteCount) Calc() {
+	v.Turnout, v.AgreeRate = 0, 0
+
+	if v.EligibleVoters > 0 {
+		v.Turnout = float64(v.ValidVotes+v.InvalidVotes) / float64(v.EligibleVoters) * 100
+	}
+
+	if v.ValidVotes > 0 {
+		v.AgreeRate = float64(v.AgreeVotes) / float64(v.ValidVotes) * 100
+	}
+}
+
+type RecallResult struct {
+	ConstituencyId uint64 `json:"constituencyId"`
+	VoteCount
+	// Threshold is the number of agree votes required by law: a quarter of
+	// the eligible voters of the constituency.
+	Threshold    uint64        `json:"threshold"`
+	AnnouncedAt  string        `json:"announcedAt"`
+	AnnounceURL  string        `json:"announceURL"`
+	Wards        []*WardResult `json:"wards"`
+	ThresholdMet bool          `json:"thresholdMet"`
+	Passed       bool          `json:"passed"`
+}
+
+func (r *RecallResult) Calc() {
+	r.VoteCount.Calc()
+
+	if r.Threshold == 0 {
+		r.Threshold = (r.EligibleVoters + 3) / 4
+	}
+
+	r.ThresholdMet = r.AgreeVotes >= r.Threshold
+	r.Passed = r.ThresholdMet && r.AgreeVotes > r.DisagreeVotes
+}
+
+type WardResult struct {
+	WardId   uint64 `json:"wardId"`
+	WardName string `json:"wardName"`
+	VoteCount
+}
+
+type Scoreboard struct {
+	Total          int    `json:"total"`
+	Ongoing        int    `json:"ongoing"`
+	Success        int    `json:"success"`
+	Failed         int    `json:"failed"`
+	Announced      int    `json:"announced"`
+	AgreeVotes     uint64 `json:"agreeVotes"`
+	DisagreeVotes  uint64 `json:"disagreeVotes"`
+	EligibleVoters uint64 `json:"eligibleVoters"`
+}
+
+func (rs RecallLegislators) ToScoreboard() *Scoreboard {
+	s := &Scoreboard{}
+	for _, l := range rs {
+		s.Total++
+		switch l.RecallStatus {
+		case RecallStatusOngoing:
+			s.Ongoing++
+		case RecallStatusSuccess:
+			s.Success++
+		case RecallStatusFailed:
+			s.Failed++
+		}
+
+		if l.Result != nil {
+			s.Announced++
+			s.AgreeVotes += l.Result.AgreeVotes
+			s.DisagreeVotes += l.Result.DisagreeVotes
+			s.EligibleVoters += l.Result.EligibleVoters
+		}
+	}
+
+	return s
+}
+
+func (r RecallLegislator) HasResult() bool {
+	if r.RecallStatus != RecallStatusSuccess && r.RecallStatus != RecallStatusFailed {
+		return false
+	}
+
+	return r.Result != nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/width"
+)
+
+// ROCYearOffset is the difference between Gregorian and ROC (Minguo) years:
+// ROC year 1 is 1912.
+const ROCYearOffset = 1911
+
+var ErrInvalidDate = errors.New("invalid date")
+
+// ROCDate is a calendar date with its year counted in the ROC calendar, as
+// written on national ID cards and recall petitions.
+type ROCDate struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+func NewROCDate(t time.Time) ROCDate {
+	return ROCDate{t.Year() - ROCYearOffset, int(t.Month()), t.Day()}
+}
+
+// ParseROCDate parses year, month and day as typed into a form. Digits may be
+// full-width, the year may carry a "民國" prefix or "年" suffix, and years
+// after ROCYearOffset are taken as Gregorian. The date must exist and must
+// not be after now.
+func ParseROCDate(year, month, day string, now time.Time) (ROCDate, error) {
+	y, err := parseDatePart(year, "民國", "年")
+	if err != nil {
+		return ROCDate{}, err
+	}
+	if y > ROCYearOffset {
+		y -= ROCYearOffset
+	}
+
+	m, err := parseDatePart(month, "", "月")
+	if err != nil {
+		return ROCDate{}, err
+	}
+
+	d, err := parseDatePart(day, "", "日")
+	if err != nil {
+		return ROCDate{}, err
+	}
+
+	date := ROCDate{y, m, d}
+	if !date.Valid() || date.Time(now.Location()).After(now) {
+		return ROCDate{}, ErrInvalidDate
+	}
+
+	return date, nil
+}
+
+func parseDatePart(s, prefix, suffix string) (int, error) {
+	s = strings.TrimSpace(width.Narrow.String(s))
+	s = strings.TrimSpace(strings.TrimPrefix(s, prefix))
+	s = strings.TrimSpace(strings.TrimSuffix(s, suffix))
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, ErrInvalidDate
+	}
+
+	return n, nil
+}
+
+// Valid reports whether the date exists in the calendar.
+func (d ROCDate) Valid() bool {
+	if d.Year < 1 || d.Month < 1 || d.Month > 12 || d.Day < 1 {
+		return false
+	}
+
+	t := d.Time(time.UTC)
+	return t.Month() == time.Month(d.Month) && t.Day() == d.Day
+}
+
+func (d ROCDate) GregorianYear() int {
+	return d.Year + ROCYearOffset
+}
+
+func (d ROCDate) Time(loc *time.Location) time.Time {
+	return time.Date(d.GregorianYear(), time.Month(d.Month), d.Day, 0, 0, 0, 0, loc)
+}
+
+// String returns the date as 民國 114 年 5 月 3 日.
+func (d ROCDate) String() string {
+	return "民國 " + strconv.Itoa(d.Year) + " 年 " + strconv.Itoa(d.Month) + " 月 " + strconv.Itoa(d.Day) + " 日"
+}
+
+// toTime converts the values templates pass to date funcs: time.Time,
+// ROCDate, and YYYY-MM-DD strings or *string. ok is false for empty or
+// malformed values.
+func toTime(v interface{}) (t time.Time, ok bool) {
+	var s string
+	switch d := v.(type) {
+	case time.Time:
+		return d, !d.IsZero()
+	case ROCDate:
+		return d.Time(time.UTC), d.Valid()
+	case string:
+		s = d
+	case *string:
+		if d != nil {
+			s = *d
+		}
+	}
+
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are layered, each layer overriding the one before: the defaults,
+// a YAML file (--config or APP_CONFIG), environment variables, then command
+// line flags. Every setting has all three names:
+//
+//	# config.yaml
+//	hostname: recall.example.tw
+//	readTimeout: 10s
+//
+//	APP_HOSTNAME=recall.example.tw ./recall-2025
+//	./recall-2025 --hostname recall.example.tw --read-timeout 10s
+//
+// Lists are comma-separated in environment variables and flags. An empty
+// value clears a string or list, e.g. APP_TRUSTED_PROXIES= trusts no proxy,
+// and leaves a number or duration as it is. Secrets have no flag, so they do
+// not show up in the process list.
+type Settings struct {
+	Env            string        `yaml:"env"`
+	Hostname       string        `yaml:"hostname"`
+	Path           string        `yaml:"path"`
+	Port           string        `yaml:"port"`
+	Scheme         string        `yaml:"scheme"`  // "" for https, or http on localhost
+	BaseURL        string        `yaml:"baseURL"` // overrides scheme, hostname and path
+	TrustedProxies []string      `yaml:"trustedProxies"`
+	RecallTerm     uint64        `yaml:"recallTerm"`
+	DataDir        string        `yaml:"dataDir"`
+	TemplatesDir   string        `yaml:"templatesDir"`
+	DisallowPaths  []string      `yaml:"disallowPaths"`
+	ReadTimeout    time.Duration `yaml:"readTimeout"`
+	WriteTimeout   time.Duration `yaml:"writeTimeout"`
+	IdleTimeout    time.Duration `yaml:"idleTimeout"`
+	AdminAccounts  string        `yaml:"adminAccounts"` // admin console is disabled when empty
+	AuditLog       string        `yaml:"auditLog"`
+
+	TurnstileSiteKey   string `yaml:"turnstileSiteKey"`
+	TurnstileSecretKey string `yaml:"turnstileSecretKey"`
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		Env:           AppEnvProduction,
+		Path:          "/",
+		Port:          "8080",
+		RecallTerm:    11,
+		DataDir:       "json-config",
+		TemplatesDir:  "templates",
+		DisallowPaths: []string{"/health/", "/apis/", "/assets/", "/admin"},
+		ReadTimeout:   10 * time.Second,
+		WriteTimeout:  10 * time.Second,
+		IdleTimeout:   120 * time.Second,
+		AuditLog:      DefaultAuditLogPath,
+	}
+}
+
+type settingField struct {
+	name   string // flag
+	env    string
+	usage  string
+	secret bool // no flag, redacted by --print-config
+	set    func(s *Settings, v string) error
+}
+
+var settingFields = []settingField{
+	{"env", "APP_ENV", "app environment: dev, stage or production", false, func(s *Settings, v string) error {
+		s.Env = v
+		return nil
+	}},
+	{"hostname", "APP_HOSTNAME", "public host name, with the port if not the default", false, func(s *Settings, v string) error {
+		s.Hostname = v
+		return nil
+	}},
+	{"path", "APP_PATH", "path the site is served under", false, func(s *Settings, v string) error {
+		s.Path = v
+		return nil
+	}},
+	{"port", "APP_PORT", "port to listen on", false, func(s *Settings, v string) error {
+		s.Port = v
+		return nil
+	}},
+	{"scheme", "APP_SCHEME", "public URL scheme: http or https (default https, or http on localhost)", false, func(s *Settings, v string) error {
+		s.Scheme = v
+		return nil
+	}},
+	{"base-url", "APP_BASE_URL", "public base URL, instead of scheme, hostname and path", false, func(s *Settings, v string) error {
+		s.BaseURL = v
+		return nil
+	}},
+	{"trusted-proxies", "APP_TRUSTED_PROXIES", "comma-separated IPs or CIDRs of trusted proxies", false, func(s *Settings, v string) error {
+		s.TrustedProxies = splitList(v)
+		return nil
+	}},
+	{"recall-term", "APP_RECALL_TERM", "term of the current campaign", false, func(s *Settings, v string) error {
+		if v == "" {
+			return nil
+		}
+		term, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid recall term %q", v)
+		}
+		s.RecallTerm = term
+		return nil
+	}},
+	{"data-dir", "APP_DATA_DIR", "json-config directory", false, func(s *Settings, v string) error {
+		s.DataDir = v
+		return nil
+	}},
+	{"templates-dir", "APP_TEMPLATES_DIR", "templates directory", false, func(s *Settings, v string) error {
+		s.TemplatesDir = v
+		return nil
+	}},
+	{"disallow-paths", "APP_DISALLOW_PATHS", "comma-separated paths robots.txt disallows", false, func(s *Settings, v string) error {
+		s.DisallowPaths = splitList(v)
+		return nil
+	}},
+	{"read-timeout", "APP_READ_TIMEOUT", "server read timeout", false, func(s *Settings, v string) error {
+		return parseDurationSetting(&s.ReadTimeout, v)
+	}},
+	{"write-timeout", "APP_WRITE_TIMEOUT", "server write timeout", false, func(s *Settings, v string) error {
+		return parseDurationSetting(&s.WriteTimeout, v)
+	}},
+	{"idle-timeout", "APP_IDLE_TIMEOUT", "server idle timeout", false, func(s *Settings, v string) error {
+		return parseDurationSetting(&s.IdleTimeout, v)
+	}},
+	{"admin-accounts", "APP_ADMIN_ACCOUNTS", "admin accounts file; the admin console is disabled without it", false, func(s *Settings, v string) error {
+		s.AdminAccounts = v
+		return nil
+	}},
+	{"audit-log", "APP_AUDIT_LOG", "audit log file", false, func(s *Settings, v string) error {
+		s.AuditLog = v
+		return nil
+	}},
+	{"turnstile-site-key", "TURNSTILE_SITE_KEY", "Cloudflare Turnstile site key", false, func(s *Settings, v string) error {
+		s.TurnstileSiteKey = v
+		return nil
+	}},
+	{"turnstile-secret-key", "TURNSTILE_SECRET_KEY", "Cloudflare Turnstile secret key", true, func(s *Settings, v string) error {
+		s.TurnstileSecretKey = v
+		return nil
+	}},
+}
+
+// LoadSettings layers the settings from args, the command line without the
+// program name. printConfig reports whether --print-config was given.
+func LoadSettings(args []string) (s *Settings, printConfig bool, err error) {
+	flags := flag.NewFlagSet("recall-2025", flag.ContinueOnError)
+	sf := newSettingsFlags(flags)
+	flags.BoolVar(&printConfig, "print-config", false, "print the settings with secrets redacted and exit")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if flags.NArg() > 0 {
+		return nil, false, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	s, err = sf.load()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	return s, printConfig, nil
+}
+
+// settingsFlags are --config and a flag for every setting that is not
+// secret, so commands read the same settings as the server.
+type settingsFlags struct {
+	configPath *string
+	values     map[string]string // string: flag name
+}
+
+func newSettingsFlags(flags *flag.FlagSet) *settingsFlags {
+	sf := &settingsFlags{
+		configPath: flags.String("config", os.Getenv("APP_CONFIG"), "YAML settings `file` (env APP_CONFIG)"),
+		values:     map[string]string{},
+	}
+
+	for _, f := range settingFields {
+		if f.secret {
+			continue
+		}
+
+		name := f.name
+		flags.Func(name, f.usage+" (env "+f.env+")", func(v string) error {
+			sf.values[name] = v
+			return nil
+		})
+	}
+
+	return sf
+}
+
+// load layers the defaults, the settings file, the environment and the
+// flags, each overriding the ones before. It does not validate the result.
+func (sf *settingsFlags) load() (*Settings, error) {
+	s := DefaultSettings()
+
+	if *sf.configPath != "" {
+		if err := s.readFile(*sf.configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range settingFields {
+		if v, ok := os.LookupEnv(f.env); ok {
+			if err := f.set(s, v); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.env, err)
+			}
+		}
+	}
+
+	for _, f := range settingFields {
+		if v, ok := sf.values[f.name]; ok {
+			if err := f.set(s, v); err != nil {
+				return nil, fmt.Errorf("--%s: %w", f.name, err)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Settings) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (s *Settings) Validate() error {
+	errs := []error{}
+
+	if !containsString(AppEnvs, s.Env) {
+		errs = append(errs, fmt.Errorf("env must be one of %s, not %q", strings.Join(AppEnvs, ", "), s.Env))
+	}
+
+	if port, err := strconv.ParseUint(s.Port, 10, 16); err != nil || port == 0 {
+		errs = append(errs, fmt.Errorf("invalid port %q", s.Port))
+	}
+
+	if s.Scheme != "" && s.Scheme != "http" && s.Scheme != "https" {
+		errs = append(errs, fmt.Errorf("scheme must be http or https, not %q", s.Scheme))
+	}
+
+	if s.BaseURL != "" {
+		u, err := url.Parse(s.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			errs = append(errs, fmt.Errorf("base URL must be an http or https URL without query, not %q", s.BaseURL))
+		}
+	}
+
+	for _, p := range s.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				errs = append(errs, fmt.Errorf("trusted proxy %q is neither an IP nor a CIDR", p))
+			}
+		}
+	}
+
+	if s.RecallTerm == 0 {
+		errs = append(errs, fmt.Errorf("recall term is required"))
+	}
+
+	for _, d := range []struct{ name, dir string }{{"data", s.DataDir}, {"templates", s.TemplatesDir}} {
+		if info, err := os.Stat(d.dir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s directory %q not found", d.name, d.dir))
+		}
+	}
+
+	for _, p := range s.DisallowPaths {
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("disallowed path %q must start with /", p))
+		}
+	}
+
+	for _, t := range []struct {
+		name    string
+		timeout time.Duration
+	}{{"read", s.ReadTimeout}, {"write", s.WriteTimeout}, {"idle", s.IdleTimeout}} {
+		if t.timeout <= 0 {
+			errs = append(errs, fmt.Errorf("%s timeout must be positive", t.name))
+		}
+	}
+
+	if s.AuditLog == "" {
+		errs = append(errs, fmt.Errorf("audit log is required"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublicURL returns the base URL of the site: BaseURL if set, or built from
+// the scheme, hostname and path.
+func (s *Settings) PublicURL() (*url.URL, error) {
+	if s.BaseURL != "" {
+		return url.Parse(strings.TrimSuffix(s.BaseURL, "/"))
+	}
+
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "localhost:" + s.Port
+	}
+
+	scheme := s.Scheme
+	if scheme == "" {
+		scheme = "https"
+		if strings.HasPrefix(hostname, "localhost") {
+			scheme = "http"
+		}
+	}
+
+	path := strings.TrimSuffix(s.Path, "/")
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return url.ParseRequestURI(scheme + "://" + hostname + path)
+}
+
+// Print writes the settings as YAML, with secrets redacted.
+func (s *Settings) Print(w io.Writer) error {
+	redacted := *s
+	if redacted.TurnstileSecretKey != "" {
+		redacted.TurnstileSecretKey = "REDACTED"
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&redacted); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func parseDurationSetting(d *time.Duration, v string) error {
+	if v == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", v)
+	}
+	*d = parsed
+	return nil
+}
+
+func splitList(v string) []string {
+	list := []string{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
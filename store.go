@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+var ErrRecallLegislatorNotFound = errors.New("recall legislator not found")
+
+// RecallStatuses are the statuses a recall can be set to.
+var RecallStatuses = []string{RecallStatusOngoing, RecallStatusAborted, RecallStatusSuccess, RecallStatusFailed}
+
+// RecallStages are the stages of a recall: two petition stages, then the vote
+// and its result.
+var RecallStages = []uint64{1, 2, 3, 4}
+
+// RecallLegislatorEdit changes the fields of a legislator the admin console
+// manages. Nil fields are left alone; an empty SafetyCutoffDate clears it.
+type RecallLegislatorEdit struct {
+	RecallStage      *uint64 `json:"recallStage,omitempty"`
+	RecallStatus     *string `json:"recallStatus,omitempty"`
+	SafetyCutoffDate *string `json:"safetyCutoffDate,omitempty"`
+	CsoURL           *string `json:"csoURL,omitempty"`
+	FormDeployed     *bool   `json:"formDeployed,omitempty"`
+}
+
+func (e *RecallLegislatorEdit) Validate() error {
+	if e.RecallStage != nil && !containsUint64(RecallStages, *e.RecallStage) {
+		return fmt.Errorf("invalid recallStage %d", *e.RecallStage)
+	}
+
+	if e.RecallStatus != nil && !containsString(RecallStatuses, *e.RecallStatus) {
+		return fmt.Errorf("invalid recallStatus %q", *e.RecallStatus)
+	}
+
+	if e.SafetyCutoffDate != nil && *e.SafetyCutoffDate != "" {
+		if _, err := time.Parse("2006-01-02", *e.SafetyCutoffDate); err != nil {
+			return fmt.Errorf("invalid safetyCutoffDate %q", *e.SafetyCutoffDate)
+		}
+	}
+
+	if e.CsoURL != nil && *e.CsoURL != "" {
+		u, err := url.Parse(*e.CsoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid csoURL %q", *e.CsoURL)
+		}
+	}
+
+	return nil
+}
+
+// members returns the edited keys of recall-legislators.json with their new
+// values, in file order.
+func (e *RecallLegislatorEdit) members() []jsonMember {
+	values := []struct {
+		key   string
+		set   bool
+		value interface{}
+	}{
+		{"recallStage", e.RecallStage != nil, e.RecallStage},
+		{"recallStatus", e.RecallStatus != nil, e.RecallStatus},
+		{"formDeployed", e.FormDeployed != nil, e.FormDeployed},
+		{"csoURL", e.CsoURL != nil, e.CsoURL},
+		{"safetyCutoffDate", e.SafetyCutoffDate != nil, e.SafetyCutoffDate},
+	}
+
+	members := []jsonMember{}
+	for _, v := range values {
+		if !v.set {
+			continue
+		}
+
+		value := v.value
+		if v.key == "safetyCutoffDate" && *e.SafetyCutoffDate == "" {
+			value = nil
+		}
+
+		members = append(members, jsonMember{Key: v.key, Value: marshalJSONUnescaped(value)})
+	}
+
+	return members
+}
+
+// UpdateRecallLegislator applies edit to the legislator of term with code in
+// recall-legislators.json and swaps in a snapshot with the change. Scheduled
+// transitions that are due are applied first, so they cannot override the
+// edit later. It returns the audit entry of the change, which has no changes
+// when edit did not change anything.
+func (a *App) UpdateRecallLegislator(term uint64, code string, edit *RecallLegislatorEdit, actor string) (*AuditEntry, error) {
+	if err := edit.Validate(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if err := a.applyTransitions(now); err != nil {
+		return nil, fmt.Errorf("transitions: %w", err)
+	}
+
+	entries, err := a.updateRecallLegislators(term, []legislatorEdit{{code, edit}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.record(changedEntries(entries), now, actor, AuditSourceAdmin); err != nil {
+		return entries[0], fmt.Errorf("audit: %w", err)
+	}
+
+	return entries[0], nil
+}
+
+// legislatorEdit is an edit of the legislator with a constituency code.
+type legislatorEdit struct {
+	code string
+	edit *RecallLegislatorEdit
+}
+
+// updateRecallLegislators applies edits, in order, to the legislators of term
+// in recall-legislators.json and swaps in a snapshot with the changes. The
+// file is written atomically and restored if the new snapshot does not load.
+// It returns an audit entry for each edit, without time, actor and source.
+// a.mu must be held.
+func (a *App) updateRecallLegislators(term uint64, edits []legislatorEdit) ([]*AuditEntry, error) {
+	path := a.Controller().DataPath(JSONConfigTermsDir, strconv.FormatUint(term, 10), JSONConfigRecallLegislators)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []jsonObject{}
+	if err := json.Unmarshal(original, &rows); err != nil {
+		return nil, err
+	}
+
+	entries := []*AuditEntry{}
+	changed := false
+	for _, e := range edits {
+		i := -1
+		for j, r := range rows {
+			var c string
+			if json.Unmarshal(r.Get("constituencyCode"), &c) == nil && c == e.code {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return nil, ErrRecallLegislatorNotFound
+		}
+		row := rows[i]
+
+		entry := &AuditEntry{Term: term, ConstituencyCode: e.code, Changes: []*AuditChange{}}
+		json.Unmarshal(row.Get("politicianName"), &entry.PoliticianName)
+
+		for _, m := range e.edit.members() {
+			from := row.Get(m.Key)
+			if bytes.Equal(from, m.Value) {
+				continue
+			}
+
+			entry.Changes = append(entry.Changes, &AuditChange{Field: m.Key, From: from, To: m.Value})
+			row = row.Set(m.Key, m.Value)
+			changed = true
+		}
+		rows[i] = row
+
+		entries = append(entries, entry)
+	}
+
+	if !changed {
+		return entries, nil
+	}
+
+	data := marshalJSONUnescaped(rows)
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return nil, err
+	}
+
+	if err := a.reload(); err != nil {
+		if restoreErr := writeFileAtomic(path, original, 0644); restoreErr != nil {
+			return nil, fmt.Errorf("%w (restoring %s: %v)", err, path, restoreErr)
+		}
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// changedEntries drops the entries without changes.
+func changedEntries(entries []*AuditEntry) []*AuditEntry {
+	changed := []*AuditEntry{}
+	for _, e := range entries {
+		if len(e.Changes) > 0 {
+			changed = append(changed, e)
+		}
+	}
+
+	return changed
+}
+
+// jsonObject is a JSON object that keeps the order of its members, so config
+// files edited by the server only change where they were edited.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+func (o jsonObject) Get(key string) json.RawMessage {
+	for _, m := range o {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+
+	return nil
+}
+
+// Set replaces the value of key in place, or appends it.
+func (o jsonObject) Set(key string, value json.RawMessage) jsonObject {
+	for i := range o {
+		if o[i].Key == key {
+			o[i].Value = value
+			return o
+		}
+	}
+
+	return append(o, jsonMember{Key: key, Value: value})
+}
+
+func (o *jsonObject) UnmarshalJSON(b []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	if t, err := decoder.Token(); err != nil || t != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object")
+	}
+
+	*o = jsonObject{}
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		*o = append(*o, jsonMember{Key: t.(string), Value: value})
+	}
+
+	_, err := decoder.Token()
+	return err
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(m.Value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// marshalJSONUnescaped is json.Marshal without escaping <, > and &, which the
+// files in json-config keep as they are, e.g. in URLs.
+func marshalJSONUnescaped(v interface{}) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		panic(err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUint64(values []uint64, n uint64) bool {
+	for _, v := range values {
+		if v == n {
+			return true
+		}
+	}
+
+	return false
+}
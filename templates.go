@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// PetitionFormTemplate renders every petition form from its FormLayout.
+const PetitionFormTemplate = "petition-form.html"
+
+// PageTemplates are the templates handlers render by a fixed name.
+var PageTemplates = []string{
+	"4xx.html",
+	"admin-form-layout.html",
+	"admin-form-layouts.html",
+	"admin-login.html",
+	"admin.html",
+	"authorization-letter.html",
+	"eligibility.html",
+	"fill-form.html",
+	"home.html",
+	"mayor-fill-form.html",
+	"mayor-thank-you.html",
+	PetitionFormTemplate,
+	"results.html",
+	"thank-you.html",
+	"vote-reminder.html",
+}
+
+// TemplateRegistry holds the templates of every locale. It is validated when
+// loaded so a missing or broken template stops the server from starting
+// rather than failing a request.
+type TemplateRegistry struct {
+	templates map[string]*template.Template // string: locale
+}
+
+// NewTemplateRegistry parses the templates matching pattern and validates them
+// against the campaigns in cfg.
+func NewTemplateRegistry(cfg *Config, pattern string) (*TemplateRegistry, error) {
+	tmpls, err := ParseTemplates(cfg.Catalogs, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := &TemplateRegistry{templates: tmpls}
+	if err := reg.Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	return reg, nil
+}
+
+// Has reports whether a template called name is defined.
+func (reg *TemplateRegistry) Has(name string) bool {
+	return reg.templates[DefaultLocale].Lookup(name) != nil
+}
+
+// ExecuteTemplate renders name in locale, or in DefaultLocale when locale has
+// no templates.
+func (reg *TemplateRegistry) ExecuteTemplate(w io.Writer, locale, name string, data interface{}) error {
+	t, exists := reg.templates[locale]
+	if !exists {
+		t = reg.templates[DefaultLocale]
+	}
+
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// Validate checks that every page template exists, then resolves the form
+// layout of every legislator still collecting signatures and of the mayor,
+// and renders each of them in every locale with sample data. All problems are
+// reported together.
+func (reg *TemplateRegistry) Validate(cfg *Config) error {
+	errs := []error{}
+	for _, name := range PageTemplates {
+		if !reg.Has(name) {
+			errs = append(errs, fmt.Errorf("templates: %s: not defined", name))
+		}
+	}
+
+	for _, data := range stageFormSamples(cfg) {
+		name := FormLayoutName(data.RecallStage, data.PoliticianName)
+		data.Layout = cfg.FormLayouts[name]
+		if data.Layout == nil {
+			errs = append(errs, fmt.Errorf("form-layouts: %s: not defined", name))
+			continue
+		}
+
+		for _, l := range Locales {
+			if err := reg.ExecuteTemplate(io.Discard, l, PetitionFormTemplate, data); err != nil {
+				errs = append(errs, fmt.Errorf("templates: %s: %s: %w", name, l, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stageFormSamples returns sample preview data for every petition form the
+// server can currently render, ordered by term.
+func stageFormSamples(cfg *Config) []*PreviewData {
+	terms := make([]uint64, 0, len(cfg.Campaigns))
+	for term := range cfg.Campaigns {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i] < terms[j]
+	})
+
+	samples := []*PreviewData{}
+	for _, term := range terms {
+		for _, l := range cfg.Campaigns[term].RecallLegislators {
+			if l.RecallStatus != RecallStatusOngoing || !l.IsPetitioning() {
+				continue
+			}
+
+			samples = append(samples, NewSamplePreviewData(cfg.AppBaseURL, l.ParticipateURL, l.PoliticianName, l.ConstituencyName, l.RecallStage))
+		}
+	}
+
+	samples = append(samples, NewSamplePreviewData(cfg.AppBaseURL, cfg.AppBaseURL.JoinPath("mayor"), MayorName, MayorCity, 2))
+
+	return samples
+}
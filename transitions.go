@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Scheduled transitions change the status of legislators when a time comes,
+// e.g. close the form once the safety cutoff date has passed, without anyone
+// editing recall-legislators.json. Each term declares them in
+// transitions.json:
+//
+//	[
+//		{"constituencyCode": "keelung-1", "at": "2025-05-03T23:59", "set": {"formDeployed": false}},
+//		{"relativeTo": "votingDate", "offsetDays": -30, "set": {"recallStage": 3}}
+//	]
+//
+// "at" is in Asia/Taipei unless it has an offset. "relativeTo" is the start
+// of a date of each legislator, moved by offsetDays; legislators without that
+// date are skipped. A transition without a constituencyCode applies to every
+// legislator of the term.
+//
+// Each transition fires once, at the first tick after it comes due: its edit
+// is written to recall-legislators.json like an admin edit, so a later admin
+// edit or reload keeps what the admins set. The audit state keeps when
+// transitions were last applied, so those that came due while the server
+// was down fire at startup.
+
+const JSONConfigTransitions = "transitions.json"
+
+// TransitionDateFields are the dates of a legislator a transition can be
+// relative to.
+var TransitionDateFields = []string{"safetyCutoffDate", "votingDate", "byElectionDate"}
+
+type ScheduledTransition struct {
+	ConstituencyCode string                `json:"constituencyCode,omitempty"`
+	At               string                `json:"at,omitempty"`
+	RelativeTo       string                `json:"relativeTo,omitempty"`
+	OffsetDays       int                   `json:"offsetDays,omitempty"`
+	Set              *RecallLegislatorEdit `json:"set"`
+
+	at time.Time
+}
+
+type ScheduledTransitions []*ScheduledTransition
+
+// config: transitions
+func ReadConfigTransitions(dir string, legislators RecallLegislators) (ScheduledTransitions, error) {
+	file, err := os.Open(filepath.Join(dir, JSONConfigTransitions))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	transitions := ScheduledTransitions{}
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&transitions); err != nil {
+		return nil, fmt.Errorf("transitions: %w", err)
+	}
+
+	codes := map[string]bool{}
+	for _, l := range legislators {
+		codes[l.ConstituencyCode] = true
+	}
+
+	for i, t := range transitions {
+		if err := t.validate(codes, Location); err != nil {
+			return nil, fmt.Errorf("transitions: #%d: %w", i+1, err)
+		}
+	}
+
+	return transitions, nil
+}
+
+func (t *ScheduledTransition) validate(codes map[string]bool, loc *time.Location) error {
+	if t.ConstituencyCode != "" && !codes[t.ConstituencyCode] {
+		return fmt.Errorf("constituency %q not found", t.ConstituencyCode)
+	}
+
+	if (t.At == "") == (t.RelativeTo == "") {
+		return fmt.Errorf("exactly one of at and relativeTo is required")
+	}
+
+	if t.At != "" {
+		at, err := time.Parse(time.RFC3339, t.At)
+		if err != nil {
+			at, err = time.ParseInLocation("2006-01-02T15:04", t.At, loc)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid at %q", t.At)
+		}
+		t.at = at
+	} else if !containsString(TransitionDateFields, t.RelativeTo) {
+		return fmt.Errorf("invalid relativeTo %q", t.RelativeTo)
+	}
+
+	if t.Set == nil || len(t.Set.members()) == 0 {
+		return fmt.Errorf("set is required")
+	}
+
+	return t.Set.Validate()
+}
+
+// Time returns when the transition is due for l, reading relative dates in
+// loc, or false if it does not apply to l.
+func (t *ScheduledTransition) Time(l *RecallLegislator, loc *time.Location) (time.Time, bool) {
+	if t.ConstituencyCode != "" && t.ConstituencyCode != l.ConstituencyCode {
+		return time.Time{}, false
+	}
+
+	if t.RelativeTo == "" {
+		return t.at, true
+	}
+
+	var date *string
+	switch t.RelativeTo {
+	case "safetyCutoffDate":
+		date = l.SafetyCutoffDate
+	case "votingDate":
+		date = l.VotingDate
+	case "byElectionDate":
+		date = l.ByElectionDate
+	}
+	if date == nil || *date == "" {
+		return time.Time{}, false
+	}
+
+	d, err := time.ParseInLocation("2006-01-02", *date, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return d.AddDate(0, 0, t.OffsetDays), true
+}
+
+// dueTransitions returns the edits of the transitions that came due after
+// since and by now, in order. Relative dates are read in the location of now.
+func (c *Campaign) dueTransitions(since, now time.Time) []legislatorEdit {
+	edits := []legislatorEdit{}
+	for _, t := range c.Transitions {
+		for _, l := range c.RecallLegislators {
+			at, ok := t.Time(l, now.Location())
+			if !ok || !at.After(since) || at.After(now) {
+				continue
+			}
+
+			edits = append(edits, legislatorEdit{l.ConstituencyCode, t.Set})
+		}
+	}
+
+	return edits
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCampaignDueTransitions(t *testing.T) {
+	c := readTestCampaign(t)
+	l := c.RecallLegislators[0]
+
+	stage := l.RecallStage%uint64(len(RecallStages)) + 1
+	transitions := ScheduledTransitions{
+		{ConstituencyCode: l.ConstituencyCode, At: "2025-01-01T00:00", Set: &RecallLegislatorEdit{RecallStage: &stage}},
+		{ConstituencyCode: l.ConstituencyCode, At: "2025-01-02T00:00", Set: &RecallLegislatorEdit{FormDeployed: new(bool)}},
+	}
+	codes := map[string]bool{l.ConstituencyCode: true}
+	for _, tr := range transitions {
+		if err := tr.validate(codes, Location); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Transitions = transitions
+
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, 1, day, hour, 0, 0, 0, Location)
+	}
+
+	tests := []struct {
+		name       string
+		since, now time.Time
+		want       int
+	}{
+		{"not yet due", time.Time{}, at(1, 0).Add(-time.Minute), 0},
+		{"due at now", time.Time{}, at(1, 0), 1},
+		{"both due since the start", time.Time{}, at(3, 0), 2},
+		{"first fired at the last tick", at(1, 0), at(1, 1), 0},
+		{"second due since the last tick", at(1, 1), at(2, 1), 1},
+		{"both fired", at(2, 1), at(3, 1), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := c.dueTransitions(tt.since, tt.now)
+			if len(edits) != tt.want {
+				t.Fatalf("dueTransitions(%v, %v) = %d edits, want %d", tt.since, tt.now, len(edits), tt.want)
+			}
+			for _, e := range edits {
+				if e.code != l.ConstituencyCode {
+					t.Errorf("edit of %s, want %s", e.code, l.ConstituencyCode)
+				}
+			}
+		})
+	}
+}